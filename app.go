@@ -3,11 +3,15 @@ package main
 import (
 	"bytes"
 	"crypto/sha256"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -16,6 +20,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/tdewolff/minify"
+	"github.com/tdewolff/minify/css"
 	"github.com/tdewolff/minify/svg"
 	"golang.org/x/net/context"
 	"gopkg.in/yaml.v2"
@@ -28,19 +33,18 @@ import (
 const (
 	badgeGenerationTimeout = 1500 * time.Millisecond
 	xSpacing               = 8
+	badgeHeight            = 20
+	badgeBaseline          = 14
 	defaultColor           = "4c1"
+	defaultLabelColor      = "555"
+	badgeMaxJSONSize       = 64 * 1024 // 64KiB
 )
 
 const (
-	colorNameBlue        = "blue"
-	colorNameBrightGreen = "brightgreen"
-	colorNameGray        = "gray"
-	colorNameGreen       = "green"
-	colorNameLightGray   = "lightgray"
-	colorNameOrange      = "orange"
-	colorNameRed         = "red"
-	colorNameYellow      = "yellow"
-	colorNameYellowGreen = "yellowgreen"
+	badgeThemeDefault = ""
+	badgeThemeAuto    = "auto"
+	badgeThemeDark    = "dark"
+	badgeThemeLight   = "light"
 )
 
 var (
@@ -55,18 +59,6 @@ var (
 	serviceHandlers = map[string]serviceHandler{}
 	version         = "dev"
 
-	colorList = map[string]string{
-		colorNameBlue:        "007ec6",
-		colorNameBrightGreen: "4c1",
-		colorNameGray:        "555",
-		colorNameGreen:       "97CA00",
-		colorNameLightGray:   "9f9f9f",
-		colorNameOrange:      "fe7d37",
-		colorNameRed:         "e05d44",
-		colorNameYellow:      "dfb317",
-		colorNameYellowGreen: "a4a61d",
-	}
-
 	cacheStore  cache.Cache
 	configStore = configStorage{}
 )
@@ -96,6 +88,79 @@ type serviceHandler interface {
 	Handle(ctx context.Context, params []string) (title, text, color string, err error)
 }
 
+// segmentedServiceHandler is implemented by service handlers able to
+// return badges with more than the title and text segments. If
+// implemented HandleSegments is used instead of Handle to render the
+// badge of the service.
+type segmentedServiceHandler interface {
+	HandleSegments(ctx context.Context, params []string) ([]badgeSegment, error)
+}
+
+type badgeSegment struct {
+	Text  string `json:"text"`
+	Color string `json:"color"`
+	Link  string `json:"link,omitempty"`
+}
+
+// badgeOptions contains the settings applying to the whole badge
+// rather than to single segments
+type badgeOptions struct {
+	// Title is the accessible name of the badge, derived from the
+	// segment texts when empty
+	Title string
+	// Theme selects the colors of the badge: light (the default) and
+	// dark render the respective colors while auto switches between
+	// them using the prefers-color-scheme media query
+	Theme string
+	// Font is the name of the registered font to render the text
+	// with, the embedded font is used when empty
+	Font string
+	// Size is the font size the dimensions of the badge are scaled
+	// with, fontSize is used when zero
+	Size int
+}
+
+// parseBadgeOptions reads the badge options from the query. For
+// invalid options the text of the error badge to render is returned.
+func parseBadgeOptions(q url.Values) (badgeOptions, string) {
+	opts := badgeOptions{
+		Title: q.Get("alt"),
+		Theme: q.Get("theme"),
+		Font:  q.Get("font"),
+		Size:  fontSize,
+	}
+
+	switch opts.Theme {
+	case badgeThemeDefault, badgeThemeAuto, badgeThemeDark, badgeThemeLight:
+	default:
+		return opts, "invalid theme"
+	}
+
+	if v := q.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < fontSizeMin || size > fontSizeMax {
+			return opts, "invalid size"
+		}
+		opts.Size = size
+	}
+
+	if _, err := fontChainFor(opts.Font); err != nil {
+		logrus.WithError(err).Debug("loading badge font")
+		return opts, "invalid font"
+	}
+
+	return opts, ""
+}
+
+// titleTextSegments converts the result of a serviceHandler into
+// the segments of the badge
+func titleTextSegments(title, text, color string) []badgeSegment {
+	return []badgeSegment{
+		{Text: title, Color: defaultLabelColor},
+		{Text: text, Color: color},
+	}
+}
+
 func registerServiceHandler(service string, f serviceHandler) {
 	if _, ok := serviceHandlers[service]; ok {
 		panic("duplicate service handler")
@@ -157,8 +222,17 @@ func main() {
 		logrus.WithError(err).Fatal("Unable to open config")
 	}
 
-	r := mux.NewRouter().UseEncodedPath()
+	if _, err = loadFontChain(); err != nil {
+		logrus.WithError(err).Fatal("Unable to load fonts")
+	}
+
+	// Path cleaning is disabled as empty path segments are used to
+	// render single segment badges (/static//sponsored/ea4aaa)
+	r := mux.NewRouter().UseEncodedPath().SkipClean(true)
 	r.HandleFunc("/v1/badge", generateBadge).Methods("GET")
+	r.HandleFunc("/v1/badge", generateBadgeFromJSON).Methods("POST")
+	r.HandleFunc("/coverage/upload/{parameters:.*}", handleCoverageUpload).Methods("POST")
+	r.HandleFunc("/push/{namespace}/{key}", handlePush).Methods("PUT")
 	r.HandleFunc("/{service}/{parameters:.*}", generateServiceBadge).Methods("GET")
 	r.HandleFunc("/", handleDemoPage)
 
@@ -197,13 +271,85 @@ func generateServiceBadge(res http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	title, text, color, err := handler.Handle(ctx, params)
+	var segments []badgeSegment
+	if sh, ok := handler.(segmentedServiceHandler); ok {
+		segments, err = sh.HandleSegments(ctx, params)
+	} else {
+		var title, text, color string
+		title, text, color, err = handler.Handle(ctx, params)
+		segments = titleTextSegments(title, text, color)
+	}
+
 	if err != nil {
 		http.Error(res, "Error while executing service: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	renderBadgeToResponse(al, r, title, text, color)
+	renderBadgeToResponse(al, r, applyBadgeOverrides(segments, r.URL.Query()))
+}
+
+// applyBadgeOverrides applies the overrides given as query parameters
+// to the result of every handler to allow customizing badges
+// regardless of the handler implementation: label and labelColor
+// apply to the first segment, color to all other segments and prefix
+// and suffix to the text following the label. Single segment badges
+// have no label, all other overrides apply to their only segment.
+// The link applies to the whole badge, leftLink and rightLink to the
+// segments of badges consisting of label and text. Empty segments are
+// not rendered and therefore never modified.
+func applyBadgeOverrides(segments []badgeSegment, q url.Values) []badgeSegment {
+	var (
+		label  = -1
+		values []int
+	)
+
+	for i := range segments {
+		switch {
+		case segments[i].Text == "":
+			// Hidden segment
+		case i == 0 && len(segments) > 1:
+			label = i
+		default:
+			values = append(values, i)
+		}
+	}
+
+	if label >= 0 {
+		if v := q.Get("label"); v != "" {
+			segments[label].Text = v
+		}
+		if v := q.Get("labelColor"); v != "" {
+			segments[label].Color = v
+		}
+	}
+
+	for _, i := range values {
+		if v := q.Get("color"); v != "" {
+			segments[i].Color = v
+		}
+	}
+
+	if len(values) > 0 {
+		segments[values[0]].Text = q.Get("prefix") + segments[values[0]].Text
+		segments[values[len(values)-1]].Text += q.Get("suffix")
+	}
+
+	for i := range segments {
+		if v := q.Get("link"); v != "" && segments[i].Text != "" {
+			segments[i].Link = v
+		}
+	}
+
+	if len(segments) == 2 { //nolint:gomnd
+		if v := q.Get("leftLink"); v != "" && segments[0].Text != "" {
+			segments[0].Link = v
+		}
+		if v := q.Get("rightLink"); v != "" && segments[1].Text != "" {
+			segments[1].Link = v
+		}
+	}
+
+	return segments
 }
 
 func generateBadge(res http.ResponseWriter, r *http.Request) {
@@ -211,7 +357,7 @@ func generateBadge(res http.ResponseWriter, r *http.Request) {
 	text := r.URL.Query().Get("text")
 	color := r.URL.Query().Get("color")
 
-	if title == "" || text == "" {
+	if title == "" && text == "" {
 		http.Error(res, "You must specify parameters 'title' and 'text'.", http.StatusInternalServerError)
 		return
 	}
@@ -220,15 +366,82 @@ func generateBadge(res http.ResponseWriter, r *http.Request) {
 		color = defaultColor
 	}
 
-	http.Redirect(res, r, fmt.Sprintf("/static/%s/%s/%s",
+	// http.Redirect is not used as it cleans the empty path segment of
+	// single segment badges from the location
+	res.Header().Set("Location", fmt.Sprintf("/static/%s/%s/%s",
 		url.QueryEscape(title),
 		url.QueryEscape(text),
 		url.QueryEscape(color),
-	), http.StatusMovedPermanently)
+	))
+	res.WriteHeader(http.StatusMovedPermanently)
+}
+
+// generateBadgeFromJSON renders the badge described by the segments
+// in the JSON request body
+func generateBadgeFromJSON(res http.ResponseWriter, r *http.Request) {
+	body := struct {
+		Segments []badgeSegment `json:"segments"`
+	}{}
+
+	if err := json.NewDecoder(http.MaxBytesReader(res, r.Body, badgeMaxJSONSize)).Decode(&body); err != nil {
+		http.Error(res, "Unable to parse JSON body", http.StatusBadRequest)
+		return
+	}
+
+	if len(body.Segments) == 0 {
+		http.Error(res, "You must specify at least one segment.", http.StatusBadRequest)
+		return
+	}
+
+	for i := range body.Segments {
+		switch {
+		case body.Segments[i].Color != "":
+			// Color given by the request
+		case i == 0 && len(body.Segments) > 1:
+			body.Segments[i].Color = defaultLabelColor
+		default:
+			body.Segments[i].Color = defaultColor
+		}
+	}
+
+	renderBadgeToResponse(accessLogger.New(res), r, body.Segments)
 }
 
-func renderBadgeToResponse(res http.ResponseWriter, r *http.Request, title, text, color string) {
-	cacheKey := fmt.Sprintf("%x", sha256.Sum256([]byte(fmt.Sprintf("%s::::%s::::%s", title, text, color))))
+func renderBadgeToResponse(res http.ResponseWriter, r *http.Request, segments []badgeSegment) {
+	opts, invalid := parseBadgeOptions(r.URL.Query())
+	if invalid != "" {
+		segments = titleTextSegments("error", invalid, colorNameRed)
+		opts = badgeOptions{}
+	}
+
+	// The error does not contain the given color or link as they must
+	// not be rendered into the SVG
+	for i, seg := range segments {
+		_, _, colorOK := parseColor(seg.Color)
+
+		var text string
+		switch {
+		case !colorOK && i == 0 && len(segments) > 1:
+			text = "invalid label color"
+		case !colorOK:
+			text = "invalid color"
+		case seg.Link != "" && !validBadgeLink(seg.Link):
+			text = "invalid link"
+		default:
+			continue
+		}
+
+		segments = titleTextSegments("error", text, colorNameRed)
+		opts = badgeOptions{}
+		break
+	}
+
+	hashInput := []string{opts.Title, opts.Theme, opts.Font, strconv.Itoa(opts.Size)}
+	for _, seg := range segments {
+		hashInput = append(hashInput, seg.Text, seg.Color, seg.Link)
+	}
+
+	cacheKey := fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(hashInput, "::::"))))
 	storedTag, _ := cacheStore.Get("eTag", cacheKey)
 
 	res.Header().Add("Cache-Control", "no-cache")
@@ -239,7 +452,7 @@ func renderBadgeToResponse(res http.ResponseWriter, r *http.Request, title, text
 		return
 	}
 
-	badge, eTag := createBadge(title, text, color)
+	badge, eTag := createSegmentedBadge(segments, opts)
 	_ = cacheStore.Set("eTag", cacheKey, eTag, time.Hour)
 
 	res.Header().Add("ETag", eTag)
@@ -247,6 +460,7 @@ func renderBadgeToResponse(res http.ResponseWriter, r *http.Request, title, text
 
 	m := minify.New()
 	m.AddFunc("image/svg+xml", svg.Minify)
+	m.AddFunc("text/css", css.Minify)
 
 	badge, _ = m.Bytes("image/svg+xml", badge)
 
@@ -255,34 +469,147 @@ func renderBadgeToResponse(res http.ResponseWriter, r *http.Request, title, text
 	}
 }
 
-func createBadge(title, text, color string) ([]byte, string) {
+func createBadge(title, text, color, labelColor string) ([]byte, string) {
+	return createSegmentedBadge([]badgeSegment{
+		{Text: title, Color: labelColor},
+		{Text: text, Color: color},
+	}, badgeOptions{})
+}
+
+// createSegmentedBadge renders the segments into the badge SVG.
+// Segments without text are left out to allow badges consisting of
+// only the label or only the text.
+func createSegmentedBadge(segments []badgeSegment, opts badgeOptions) ([]byte, string) {
 	var buf bytes.Buffer
 
-	titleW, _ := calculateTextWidth(title)
-	textW, _ := calculateTextWidth(text)
+	t, _ := assets.ReadFile("assets/badgeTemplate.tpl")
+	tpl, _ := template.New("svg").Funcs(template.FuncMap{"xml": xmlEscape}).Parse(string(t))
 
-	width := titleW + textW + 4*xSpacing //nolint:gomnd
+	type renderSegment struct {
+		Text                  string
+		X, Width, Anchor      int
+		Color                 string
+		TextColor, TextShadow string
+		Link                  string
+		Direction             string
 
-	t, _ := assets.ReadFile("assets/badgeTemplate.tpl")
-	tpl, _ := template.New("svg").Parse(string(t))
+		DarkColor, DarkTextColor, DarkTextShadow string
+	}
 
-	if c, ok := colorList[color]; ok {
-		color = c
+	title := opts.Title
+	if title == "" {
+		title = badgeAccessibleTitle(segments)
+	}
+
+	// Options are validated before rendering, the default font is
+	// used in case the font vanished in between
+	fonts, err := fontChainFor(opts.Font)
+	if err != nil {
+		fonts, _ = loadFontChain()
+	}
+
+	size := opts.Size
+	if size == 0 {
+		size = fontSize
+	}
+
+	// The dimensions of the badge are designed for the default font
+	// size and scaled along with the font size
+	scaled := func(v int) int { return int(math.Round(float64(v*size) / fontSize)) }
+	spacing := scaled(xSpacing)
+
+	var (
+		rendered = make([]renderSegment, 0, len(segments))
+		width    int
+		hasLinks bool
+	)
+
+	for i, seg := range segments {
+		if seg.Text == "" {
+			continue
+		}
+
+		// Colors are validated before rendering, invalid colors are
+		// never passed into the SVG
+		color, bg, ok := parseColor(seg.Color)
+		if !ok {
+			def := defaultColor
+			if i == 0 && len(segments) > 1 {
+				def = defaultLabelColor
+			}
+			color, bg, _ = parseColor(def)
+		}
+
+		textW := measureText(seg.Text, fonts, size)
+		textColor, textShadow := textColors(bg)
+
+		dark := darkThemeColor(bg)
+		darkTextColor, darkTextShadow := textColors(dark)
+		if opts.Theme == badgeThemeDark {
+			color, textColor, textShadow = dark.Hex(), darkTextColor, darkTextShadow
+		}
+
+		rendered = append(rendered, renderSegment{
+			Text:       seg.Text,
+			X:          width,
+			Width:      textW + 2*spacing, //nolint:gomnd
+			Anchor:     width + textW/2 + spacing,
+			Color:      color,
+			TextColor:  textColor,
+			TextShadow: textShadow,
+			Link:       seg.Link,
+			Direction:  textDirection(seg.Text),
+
+			DarkColor:      dark.Hex(),
+			DarkTextColor:  darkTextColor,
+			DarkTextShadow: darkTextShadow,
+		})
+		hasLinks = hasLinks || seg.Link != ""
+
+		width += textW + 2*spacing //nolint:gomnd
 	}
 
 	_ = tpl.Execute(&buf, map[string]any{
-		"Width":       width,
-		"TitleWidth":  titleW + 2*xSpacing,
-		"Title":       title,
-		"Text":        text,
-		"TitleAnchor": titleW/2 + xSpacing,
-		"TextAnchor":  titleW + textW/2 + 3*xSpacing,
-		"Color":       color,
+		"Width":      width,
+		"Segments":   rendered,
+		"HasLinks":   hasLinks,
+		"Title":      title,
+		"AutoTheme":  opts.Theme == badgeThemeAuto,
+		"FontFamily": fontFamilies(fonts),
+		"FontSize":   size,
+		"Height":     scaled(badgeHeight),
+		"Baseline":   scaled(badgeBaseline),
+		"Shadow":     scaled(badgeBaseline + 1),
 	})
 
 	return buf.Bytes(), generateETag(buf.Bytes())
 }
 
+// badgeAccessibleTitle describes the badge for screen readers in the
+// form "label: text" or "label: text, text, ..." for badges having
+// more than two segments
+func badgeAccessibleTitle(segments []badgeSegment) string {
+	var texts []string
+	for _, seg := range segments {
+		if seg.Text != "" {
+			texts = append(texts, seg.Text)
+		}
+	}
+
+	if len(texts) < 2 { //nolint:gomnd
+		return strings.Join(texts, "")
+	}
+
+	return texts[0] + ": " + strings.Join(texts[1:], ", ")
+}
+
+// xmlEscape escapes the value for use in text and attributes of the SVG
+func xmlEscape(in string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(in))
+	return buf.String()
+}
+
 func generateETag(in []byte) string {
 	return fmt.Sprintf("%x", sha256.Sum256(in))
 }
@@ -1,20 +1,38 @@
-<svg xmlns="http://www.w3.org/2000/svg" width="{{ .Width }}" height="20">
+<svg xmlns="http://www.w3.org/2000/svg" width="{{ .Width }}" height="{{ .Height }}" role="img" aria-label="{{ xml .Title }}"{{ if .HasLinks }} xmlns:xlink="http://www.w3.org/1999/xlink"{{ end }}>
+   <title>{{ xml .Title }}</title>
+   {{- if .AutoTheme }}
+   <style>
+      @media (prefers-color-scheme: dark) {
+         {{- range $i, $s := .Segments }}
+         .s{{ $i }} { fill: #{{ $s.DarkColor }} }
+         .t{{ $i }} { fill: #{{ $s.DarkTextColor }} }
+         .h{{ $i }} { fill: #{{ $s.DarkTextShadow }} }
+         {{- end }}
+      }
+   </style>
+   {{- end }}
    <linearGradient id="b" x2="0" y2="100%">
       <stop offset="0" stop-color="#bbb" stop-opacity=".1" />
       <stop offset="1"                   stop-opacity=".1" />
    </linearGradient>
    <mask id="a">
-      <rect width="{{ .Width }}" height="20" rx="3" fill="#fff" />
+      <rect width="{{ .Width }}" height="{{ .Height }}" rx="3" fill="#fff" />
    </mask>
    <g mask="url(#a)">
-      <path fill="#555"             d="M0                 0 h{{ .TitleWidth }}  v20 H0                 z" />
-      <path fill="#{{ .Color }}"    d="M{{ .TitleWidth }} 0 H{{ .Width }}       v20 H{{ .TitleWidth }} z" />
-      <path fill="url(#b)"          d="M0                 0 h{{ .Width }}       v20 H0                 z" />
+      {{- range $i, $s := .Segments }}
+      <path{{ if $.AutoTheme }} class="s{{ $i }}"{{ end }} fill="#{{ .Color }}" d="M{{ .X }} 0 h{{ .Width }} v{{ $.Height }} H{{ .X }} z" />
+      {{- end }}
+      <path fill="url(#b)"      d="M0 0 h{{ .Width }} v{{ $.Height }} H0 z" />
    </g>
-   <g fill="#fff" text-anchor="middle" font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="11">
-      <text x="{{ .TitleAnchor }}" y="15" fill="#010101" fill-opacity=".3">{{ .Title }}</text>
-      <text x="{{ .TitleAnchor }}" y="14"                                 >{{ .Title }}</text>
-      <text x="{{ .TextAnchor }}"  y="15" fill="#010101" fill-opacity=".3">{{ .Text }}</text>
-      <text x="{{ .TextAnchor }}"  y="14"                                 >{{ .Text }}</text>
+   <g text-anchor="middle" font-family="{{ xml .FontFamily }}" font-size="{{ .FontSize }}">
+      {{- range $i, $s := .Segments }}
+      <text{{ if $.AutoTheme }} class="h{{ $i }}"{{ end }} x="{{ .Anchor }}" y="{{ $.Shadow }}" fill="#{{ .TextShadow }}" fill-opacity=".3"{{ if eq .Direction "rtl" }} direction="rtl"{{ end }}>{{ xml .Text }}</text>
+      <text{{ if $.AutoTheme }} class="t{{ $i }}"{{ end }} x="{{ .Anchor }}" y="{{ $.Baseline }}" fill="#{{ .TextColor }}"{{ if eq .Direction "rtl" }} direction="rtl"{{ end }}>{{ xml .Text }}</text>
+      {{- end }}
    </g>
+   {{- range .Segments }}{{ if .Link }}
+   <a target="_blank" href="{{ xml .Link }}" xlink:href="{{ xml .Link }}">
+      <rect x="{{ .X }}" width="{{ .Width }}" height="{{ $.Height }}" fill="rgba(0,0,0,0)" />
+   </a>
+   {{- end }}{{ end }}
 </svg>
@@ -11,7 +11,8 @@ import (
 // ErrKeyNotFound signalized the key is not present in the cache
 var ErrKeyNotFound = errors.New("requested key was not found in database")
 
-// Cache describes an interface used to store generated data
+// Cache describes an interface used to store generated data. Values
+// stored with a TTL of zero or less do not expire.
 type Cache interface {
 	Get(namespace, key string) (value string, err error)
 	Set(namespace, key, value string, ttl time.Duration) (err error)
@@ -5,6 +5,10 @@ import (
 	"time"
 )
 
+// inMemCacheSweepInterval is the minimum time between two sweeps
+// removing the expired entries from the cache
+const inMemCacheSweepInterval = time.Minute
+
 type inMemCacheEntry struct {
 	Value   string
 	Expires time.Time
@@ -12,8 +16,9 @@ type inMemCacheEntry struct {
 
 // InMemCache implements the Cache interface for storage in memory
 type InMemCache struct {
-	cache map[string]inMemCacheEntry
-	lock  sync.RWMutex
+	cache     map[string]inMemCacheEntry
+	lastSweep time.Time
+	lock      sync.RWMutex
 }
 
 // NewInMemCache creates a new InMemCache
@@ -29,7 +34,7 @@ func (i *InMemCache) Get(namespace, key string) (value string, err error) {
 	defer i.lock.RUnlock()
 
 	e, ok := i.cache[namespace+"::"+key]
-	if !ok || e.Expires.Before(time.Now()) {
+	if !ok || (!e.Expires.IsZero() && e.Expires.Before(time.Now())) {
 		return "", ErrKeyNotFound
 	}
 	return e.Value, nil
@@ -40,9 +45,14 @@ func (i *InMemCache) Set(namespace, key, value string, ttl time.Duration) (err e
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
-	i.cache[namespace+"::"+key] = inMemCacheEntry{
-		Value:   value,
-		Expires: time.Now().Add(ttl),
+	e := inMemCacheEntry{Value: value}
+	if ttl > 0 {
+		e.Expires = time.Now().Add(ttl)
+	}
+	i.cache[namespace+"::"+key] = e
+
+	if time.Since(i.lastSweep) > inMemCacheSweepInterval {
+		i.sweep()
 	}
 
 	return nil
@@ -56,3 +66,15 @@ func (i *InMemCache) Delete(namespace, key string) (err error) {
 	delete(i.cache, namespace+"::"+key)
 	return nil
 }
+
+// sweep removes the expired entries which otherwise would stay in
+// memory until overwritten, the lock must be held by the caller
+func (i *InMemCache) sweep() {
+	now := time.Now()
+	for k, e := range i.cache {
+		if !e.Expires.IsZero() && e.Expires.Before(now) {
+			delete(i.cache, k)
+		}
+	}
+	i.lastSweep = now
+}
@@ -0,0 +1,29 @@
+package main
+
+const ciStatusUnknown = "unknown"
+
+// ciStatusColors maps the normalized build states reported by the CI
+// handlers to their badge colors. Handlers should translate their
+// provider specific states into one of these before rendering.
+var ciStatusColors = map[string]string{
+	"passed":   colorNameBrightGreen,
+	"unstable": colorNameYellow,
+	"failed":   colorNameRed,
+	"errored":  colorNameOrange,
+	"canceled": colorNameLightGray,
+	"stopped":  colorNameLightGray,
+	"skipped":  colorNameLightGray,
+	"blocked":  colorNameLightGray,
+	"running":  colorNameYellow,
+	"pending":  colorNameYellow,
+	"started":  colorNameYellow,
+	"created":  colorNameYellow,
+	"received": colorNameYellow,
+}
+
+func ciStatusColor(state string) string {
+	if c, ok := ciStatusColors[state]; ok {
+		return c
+	}
+	return colorNameLightGray
+}
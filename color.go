@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	colorNameBlue        = "blue"
+	colorNameBrightGreen = "brightgreen"
+	colorNameGray        = "gray"
+	colorNameGreen       = "green"
+	colorNameLightGray   = "lightgray"
+	colorNameOrange      = "orange"
+	colorNameRed         = "red"
+	colorNameYellow      = "yellow"
+	colorNameYellowGreen = "yellowgreen"
+)
+
+// colorLightThreshold is the YIQ brightness above which a background
+// gets dark text. The value matches the one used by shields.io to
+// keep the text of the well-known badge colors white.
+const colorLightThreshold = 0.69
+
+// colorDarkThemeFactor is applied to the backgrounds of the dark theme
+// to reduce their glare on dark pages
+const colorDarkThemeFactor = 0.8
+
+// colorDarkThemeLabel replaces the default label color in the dark
+// theme as the gray label looks washed out on dark pages
+var colorDarkThemeLabel = rgbColor{R: 0x30, G: 0x36, B: 0x3d} //nolint:gomnd
+
+var (
+	// colorList contains the badge colors, taking precedence over the
+	// CSS color names of the same name
+	colorList = map[string]string{
+		colorNameBlue:        "007ec6",
+		colorNameBrightGreen: "4c1",
+		colorNameGray:        "555",
+		colorNameGreen:       "97CA00",
+		colorNameLightGray:   "9f9f9f",
+		colorNameOrange:      "fe7d37",
+		colorNameRed:         "e05d44",
+		colorNameYellow:      "dfb317",
+		colorNameYellowGreen: "a4a61d",
+
+		// Aliases known from shields.io
+		"grey":          "555",
+		"lightgrey":     "9f9f9f",
+		"success":       "4c1",
+		"important":     "fe7d37",
+		"critical":      "e05d44",
+		"informational": "007ec6",
+		"inactive":      "9f9f9f",
+	}
+
+	colorHexNotation  = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+	colorFuncNotation = regexp.MustCompile(`^(rgba?|hsla?)\(([^)]*)\)$`)
+
+	// cssColorNames contains the named colors of CSS Color Module
+	// Level 4, the badge color names in colorList take precedence
+	cssColorNames = map[string]string{
+		"aliceblue": "f0f8ff", "antiquewhite": "faebd7", "aqua": "00ffff", "aquamarine": "7fffd4",
+		"azure": "f0ffff", "beige": "f5f5dc", "bisque": "ffe4c4", "black": "000000",
+		"blanchedalmond": "ffebcd", "blue": "0000ff", "blueviolet": "8a2be2", "brown": "a52a2a",
+		"burlywood": "deb887", "cadetblue": "5f9ea0", "chartreuse": "7fff00", "chocolate": "d2691e",
+		"coral": "ff7f50", "cornflowerblue": "6495ed", "cornsilk": "fff8dc", "crimson": "dc143c",
+		"cyan": "00ffff", "darkblue": "00008b", "darkcyan": "008b8b", "darkgoldenrod": "b8860b",
+		"darkgray": "a9a9a9", "darkgreen": "006400", "darkgrey": "a9a9a9", "darkkhaki": "bdb76b",
+		"darkmagenta": "8b008b", "darkolivegreen": "556b2f", "darkorange": "ff8c00", "darkorchid": "9932cc",
+		"darkred": "8b0000", "darksalmon": "e9967a", "darkseagreen": "8fbc8f", "darkslateblue": "483d8b",
+		"darkslategray": "2f4f4f", "darkslategrey": "2f4f4f", "darkturquoise": "00ced1", "darkviolet": "9400d3",
+		"deeppink": "ff1493", "deepskyblue": "00bfff", "dimgray": "696969", "dimgrey": "696969",
+		"dodgerblue": "1e90ff", "firebrick": "b22222", "floralwhite": "fffaf0", "forestgreen": "228b22",
+		"fuchsia": "ff00ff", "gainsboro": "dcdcdc", "ghostwhite": "f8f8ff", "gold": "ffd700",
+		"goldenrod": "daa520", "gray": "808080", "green": "008000", "greenyellow": "adff2f",
+		"grey": "808080", "honeydew": "f0fff0", "hotpink": "ff69b4", "indianred": "cd5c5c",
+		"indigo": "4b0082", "ivory": "fffff0", "khaki": "f0e68c", "lavender": "e6e6fa",
+		"lavenderblush": "fff0f5", "lawngreen": "7cfc00", "lemonchiffon": "fffacd", "lightblue": "add8e6",
+		"lightcoral": "f08080", "lightcyan": "e0ffff", "lightgoldenrodyellow": "fafad2", "lightgray": "d3d3d3",
+		"lightgreen": "90ee90", "lightgrey": "d3d3d3", "lightpink": "ffb6c1", "lightsalmon": "ffa07a",
+		"lightseagreen": "20b2aa", "lightskyblue": "87cefa", "lightslategray": "778899", "lightslategrey": "778899",
+		"lightsteelblue": "b0c4de", "lightyellow": "ffffe0", "lime": "00ff00", "limegreen": "32cd32",
+		"linen": "faf0e6", "magenta": "ff00ff", "maroon": "800000", "mediumaquamarine": "66cdaa",
+		"mediumblue": "0000cd", "mediumorchid": "ba55d3", "mediumpurple": "9370db", "mediumseagreen": "3cb371",
+		"mediumslateblue": "7b68ee", "mediumspringgreen": "00fa9a", "mediumturquoise": "48d1cc", "mediumvioletred": "c71585",
+		"midnightblue": "191970", "mintcream": "f5fffa", "mistyrose": "ffe4e1", "moccasin": "ffe4b5",
+		"navajowhite": "ffdead", "navy": "000080", "oldlace": "fdf5e6", "olive": "808000",
+		"olivedrab": "6b8e23", "orange": "ffa500", "orangered": "ff4500", "orchid": "da70d6",
+		"palegoldenrod": "eee8aa", "palegreen": "98fb98", "paleturquoise": "afeeee", "palevioletred": "db7093",
+		"papayawhip": "ffefd5", "peachpuff": "ffdab9", "peru": "cd853f", "pink": "ffc0cb",
+		"plum": "dda0dd", "powderblue": "b0e0e6", "purple": "800080", "rebeccapurple": "663399",
+		"red": "ff0000", "rosybrown": "bc8f8f", "royalblue": "4169e1", "saddlebrown": "8b4513",
+		"salmon": "fa8072", "sandybrown": "f4a460", "seagreen": "2e8b57", "seashell": "fff5ee",
+		"sienna": "a0522d", "silver": "c0c0c0", "skyblue": "87ceeb", "slateblue": "6a5acd",
+		"slategray": "708090", "slategrey": "708090", "snow": "fffafa", "springgreen": "00ff7f",
+		"steelblue": "4682b4", "tan": "d2b48c", "teal": "008080", "thistle": "d8bfd8",
+		"tomato": "ff6347", "turquoise": "40e0d0", "violet": "ee82ee", "wheat": "f5deb3",
+		"white": "ffffff", "whitesmoke": "f5f5f5", "yellow": "ffff00", "yellowgreen": "9acd32",
+	}
+)
+
+type rgbColor struct {
+	R, G, B uint8
+}
+
+// parseColor resolves a badge color name, CSS color name, hex notation
+// (with or without leading #) or rgb() / hsl() notation. The color is
+// returned as hex notation without leading # for use in the template.
+func parseColor(in string) (string, rgbColor, bool) {
+	in = strings.TrimSpace(in)
+	lower := strings.ToLower(in)
+
+	if hex, ok := colorList[lower]; ok {
+		return hex, parseHexColor(hex), true
+	}
+
+	if hex, ok := cssColorNames[lower]; ok {
+		return hex, parseHexColor(hex), true
+	}
+
+	if m := colorHexNotation.FindStringSubmatch(in); m != nil {
+		return m[1], parseHexColor(m[1]), true
+	}
+
+	if m := colorFuncNotation.FindStringSubmatch(lower); m != nil {
+		c, ok := parseColorFunc(m[1], m[2])
+		return c.Hex(), c, ok
+	}
+
+	return "", rgbColor{}, false
+}
+
+// parseHexColor converts a validated 3 or 6 digit hex color
+func parseHexColor(hex string) rgbColor {
+	if len(hex) == 3 { //nolint:gomnd
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+
+	v, _ := strconv.ParseUint(hex, 16, 32)
+	return rgbColor{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v)} //nolint:gomnd
+}
+
+// parseColorFunc parses the arguments of rgb() / hsl() in both the
+// comma and the space separated syntax. The alpha channel is ignored
+// as badges are always opaque.
+func parseColorFunc(fn, args string) (rgbColor, bool) {
+	args, _, _ = strings.Cut(args, "/")
+	parts := strings.FieldsFunc(args, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(parts) < 3 || len(parts) > 4 { //nolint:gomnd
+		return rgbColor{}, false
+	}
+
+	var v [3]float64
+	for i := range v {
+		p := parts[i]
+		percent := strings.HasSuffix(p, "%")
+		p = strings.TrimSuffix(strings.TrimSuffix(p, "%"), "deg")
+
+		f, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return rgbColor{}, false
+		}
+
+		switch {
+		case strings.HasPrefix(fn, "rgb") && percent:
+			f = f * 255 / 100 //nolint:gomnd
+		case strings.HasPrefix(fn, "hsl") && i > 0:
+			if !percent {
+				return rgbColor{}, false
+			}
+			f /= 100
+		}
+
+		v[i] = f
+	}
+
+	if strings.HasPrefix(fn, "hsl") {
+		return hslToRGB(v[0], v[1], v[2]), true
+	}
+
+	return rgbColor{R: clampColor(v[0]), G: clampColor(v[1]), B: clampColor(v[2])}, true
+}
+
+// hslToRGB converts hue (degrees), saturation and lightness (0-1)
+func hslToRGB(h, s, l float64) rgbColor {
+	h = math.Mod(math.Mod(h, 360)+360, 360) / 360 //nolint:gomnd
+	s, l = math.Max(0, math.Min(1, s)), math.Max(0, math.Min(1, l))
+
+	q := l + s - l*s
+	if l < 0.5 { //nolint:gomnd
+		q = l * (1 + s)
+	}
+	p := 2*l - q //nolint:gomnd
+
+	hue := func(t float64) float64 {
+		t = math.Mod(t+1, 1)
+		switch {
+		case t < 1.0/6: //nolint:gomnd
+			return p + (q-p)*6*t //nolint:gomnd
+		case t < 1.0/2: //nolint:gomnd
+			return q
+		case t < 2.0/3: //nolint:gomnd
+			return p + (q-p)*(2.0/3-t)*6 //nolint:gomnd
+		default:
+			return p
+		}
+	}
+
+	return rgbColor{
+		R: clampColor(hue(h+1.0/3) * 255), //nolint:gomnd
+		G: clampColor(hue(h) * 255),       //nolint:gomnd
+		B: clampColor(hue(h-1.0/3) * 255), //nolint:gomnd
+	}
+}
+
+func clampColor(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(255, v)))) //nolint:gomnd
+}
+
+func (c rgbColor) Hex() string {
+	return fmt.Sprintf("%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// IsLight reports whether the color is too bright for white text
+func (c rgbColor) IsLight() bool {
+	yiq := (299*float64(c.R) + 587*float64(c.G) + 114*float64(c.B)) / 1000 / 255 //nolint:gomnd
+	return yiq > colorLightThreshold
+}
+
+// textColors returns the text and text shadow color readable on the
+// given background
+func textColors(bg rgbColor) (text, shadow string) {
+	if bg.IsLight() {
+		return "333", "ccc"
+	}
+	return "fff", "010101"
+}
+
+// darkThemeColor returns the background used in place of the given
+// one when rendering the dark theme
+func darkThemeColor(c rgbColor) rgbColor {
+	if c == parseHexColor(defaultLabelColor) {
+		return colorDarkThemeLabel
+	}
+
+	return rgbColor{
+		R: clampColor(float64(c.R) * colorDarkThemeFactor),
+		G: clampColor(float64(c.G) * colorDarkThemeFactor),
+		B: clampColor(float64(c.B) * colorDarkThemeFactor),
+	}
+}
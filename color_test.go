@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestParseColor(t *testing.T) {
+	cases := map[string]string{
+		"4c1":                      "4c1",
+		"#97CA00":                  "97CA00",
+		"brightgreen":              "4c1",
+		"Blue":                     "007ec6",
+		"rebeccapurple":            "663399",
+		"success":                  "4c1",
+		"critical":                 "e05d44",
+		"lightgrey":                "9f9f9f",
+		"rgb(255, 128, 0)":         "ff8000",
+		"rgb(100% 0% 0% / 50%)":    "ff0000",
+		"rgba(0,0,255,0.5)":        "0000ff",
+		"hsl(120, 100%, 25%)":      "008000",
+		"hsl(0deg 0% 100%)":        "ffffff",
+		"hsla(240, 100%, 50%, .3)": "0000ff",
+	}
+
+	for in, expect := range cases {
+		if c, _, ok := parseColor(in); !ok || c != expect {
+			t.Errorf("Parsed color %q (%v) of %q did not match %q", c, ok, in, expect)
+		}
+	}
+
+	for _, in := range []string{"", "nocolor", "12345", "rgb(1,2)", "hsl(1, 2, 3)", "#ggg"} {
+		if c, _, ok := parseColor(in); ok {
+			t.Errorf("Invalid color %q was parsed as %q", in, c)
+		}
+	}
+}
+
+func TestColorIsLight(t *testing.T) {
+	cases := map[string]bool{
+		"brightgreen": false,
+		"yellow":      false,
+		"555":         false,
+		"white":       true,
+		"lightyellow": true,
+		"fe7d37":      false,
+		"ddd":         true,
+	}
+
+	for in, expect := range cases {
+		if _, c, _ := parseColor(in); c.IsLight() != expect {
+			t.Errorf("Brightness of %q did not match light=%v", in, expect)
+		}
+	}
+}
+
+func TestDarkThemeColor(t *testing.T) {
+	cases := map[string]string{
+		"555":    "30363d",
+		"gray":   "30363d",
+		"4c1":    "36a30e",
+		"ffffff": "cccccc",
+	}
+
+	for in, expect := range cases {
+		if _, c, _ := parseColor(in); darkThemeColor(c).Hex() != expect {
+			t.Errorf("Dark theme color %q of %q did not match %q", darkThemeColor(c).Hex(), in, expect)
+		}
+	}
+}
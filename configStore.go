@@ -29,3 +29,33 @@ func (c configStorage) Int64(name string) int64 {
 
 	return 0
 }
+
+func (c configStorage) StrSlice(name string) []string {
+	v, ok := c[name]
+
+	if !ok {
+		return nil
+	}
+
+	sv, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	out := make([]string, 0, len(sv))
+	for _, e := range sv {
+		if s, ok := e.(string); ok {
+			out = append(out, s)
+		}
+	}
+
+	return out
+}
+
+func (c configStorage) StrDefault(name, def string) string {
+	if v := c.Str(name); v != "" {
+		return v
+	}
+
+	return def
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	coverageFormatGo        = "go"
+	coverageFormatCobertura = "cobertura"
+	coverageFormatLCOV      = "lcov"
+)
+
+// coverageColor maps the percentage onto a red to green scale
+func coverageColor(percent float64) string {
+	switch {
+	case percent >= 90: //nolint:gomnd
+		return colorNameBrightGreen
+	case percent >= 80: //nolint:gomnd
+		return colorNameGreen
+	case percent >= 70: //nolint:gomnd
+		return colorNameYellowGreen
+	case percent >= 60: //nolint:gomnd
+		return colorNameYellow
+	case percent >= 50: //nolint:gomnd
+		return colorNameOrange
+	default:
+		return colorNameRed
+	}
+}
+
+// coverageText formats the percentage with one decimal, rounding down
+// to not report full coverage for an almost fully covered project
+func coverageText(percent float64) string {
+	return strconv.FormatFloat(math.Floor(percent*10)/10, 'f', -1, 64) + "%" //nolint:gomnd
+}
+
+// detectCoverageFormat guesses the format of the coverage report from
+// its content and returns an empty string if it is not known
+func detectCoverageFormat(report []byte) string {
+	trimmed := bytes.TrimSpace(report)
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("mode:")):
+		return coverageFormatGo
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return coverageFormatCobertura
+	case bytes.HasPrefix(trimmed, []byte("TN:")), bytes.HasPrefix(trimmed, []byte("SF:")):
+		return coverageFormatLCOV
+	default:
+		return ""
+	}
+}
+
+// parseCoverageReport calculates the covered percentage from a report
+// in the given format
+func parseCoverageReport(format string, report []byte) (float64, error) {
+	switch format {
+	case coverageFormatGo:
+		return parseGoCoverage(report)
+	case coverageFormatCobertura:
+		return parseCoberturaCoverage(report)
+	case coverageFormatLCOV:
+		return parseLCOVCoverage(report)
+	default:
+		return 0, errors.Errorf("unknown coverage format %q", format)
+	}
+}
+
+// parseGoCoverage reads a Go cover profile. Blocks reported multiple
+// times (as happens with -coverpkg) are counted once and covered if
+// any of the entries has a non-zero count.
+func parseGoCoverage(report []byte) (float64, error) {
+	type block struct {
+		statements int64
+		covered    bool
+	}
+
+	blocks := map[string]block{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(report))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+
+		// file.go:1.2,3.4 <statements> <count>
+		fields := strings.Fields(line)
+		if len(fields) != 3 { //nolint:gomnd
+			return 0, errors.Errorf("invalid cover profile line %q", line)
+		}
+
+		statements, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, errors.Wrap(err, "parsing statement count")
+		}
+
+		count, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return 0, errors.Wrap(err, "parsing execution count")
+		}
+
+		b := blocks[fields[0]]
+		b.statements = statements
+		b.covered = b.covered || count > 0
+		blocks[fields[0]] = b
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, errors.Wrap(err, "reading cover profile")
+	}
+
+	var total, covered int64
+	for _, b := range blocks {
+		total += b.statements
+		if b.covered {
+			covered += b.statements
+		}
+	}
+
+	return coveragePercent(covered, total), nil
+}
+
+// parseCoberturaCoverage reads the line coverage from the attributes
+// of the Cobertura root element
+func parseCoberturaCoverage(report []byte) (float64, error) {
+	r := struct {
+		XMLName      xml.Name `xml:"coverage"`
+		LineRate     *float64 `xml:"line-rate,attr"`
+		LinesCovered *int64   `xml:"lines-covered,attr"`
+		LinesValid   *int64   `xml:"lines-valid,attr"`
+	}{}
+
+	if err := xml.Unmarshal(report, &r); err != nil {
+		return 0, errors.Wrap(err, "parsing Cobertura report")
+	}
+
+	switch {
+	case r.LinesCovered != nil && r.LinesValid != nil:
+		return coveragePercent(*r.LinesCovered, *r.LinesValid), nil
+	case r.LineRate != nil:
+		return *r.LineRate * 100, nil //nolint:gomnd
+	default:
+		return 0, errors.New("Cobertura report contains no line coverage")
+	}
+}
+
+// parseLCOVCoverage sums up the line coverage of all source files in
+// the LCOV tracefile. Files without LF / LH summary are counted from
+// their DA records.
+func parseLCOVCoverage(report []byte) (float64, error) {
+	var (
+		total, covered       int64
+		fileDA, fileDAHit    int64
+		fileHasSummary, inSF bool
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(report))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+
+		switch key {
+		case "SF":
+			inSF, fileHasSummary, fileDA, fileDAHit = true, false, 0, 0
+
+		case "DA":
+			// DA:<line>,<hits>[,<checksum>]
+			fields := strings.Split(value, ",")
+			if len(fields) < 2 { //nolint:gomnd
+				return 0, errors.Errorf("invalid DA record %q", value)
+			}
+			fileDA++
+			if fields[1] != "0" {
+				fileDAHit++
+			}
+
+		case "LF", "LH":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return 0, errors.Wrapf(err, "parsing %s record", key)
+			}
+			fileHasSummary = true
+			if key == "LF" {
+				total += n
+			} else {
+				covered += n
+			}
+
+		case "end_of_record":
+			if inSF && !fileHasSummary {
+				total += fileDA
+				covered += fileDAHit
+			}
+			inSF = false
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, errors.Wrap(err, "reading LCOV report")
+	}
+
+	return coveragePercent(covered, total), nil
+}
+
+func coveragePercent(covered, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(covered) / float64(total) * 100 //nolint:gomnd
+}
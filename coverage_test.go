@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCoverageReport(t *testing.T) {
+	cases := map[string]float64{
+		`mode: set
+github.com/a/b/main.go:5.13,7.2 2 1
+github.com/a/b/main.go:9.13,11.2 2 0
+github.com/a/b/main.go:9.13,11.2 2 1
+github.com/a/b/main.go:13.13,15.2 4 0
+`: 50,
+		`<?xml version="1.0" ?>
+<coverage line-rate="0.5" lines-covered="3" lines-valid="4" version="1.9"></coverage>`: 75,
+		`<coverage line-rate="0.25"></coverage>`: 25,
+		`TN:
+SF:src/a.js
+DA:1,1
+DA:2,0
+LF:2
+LH:1
+end_of_record
+SF:src/b.js
+DA:1,3
+DA:2,1
+end_of_record
+`: 75,
+	}
+
+	for report, expect := range cases {
+		format := detectCoverageFormat([]byte(report))
+		p, err := parseCoverageReport(format, []byte(report))
+		if err != nil {
+			t.Errorf("Parsing %s report failed: %s", format, err)
+			continue
+		}
+		if p != expect {
+			t.Errorf("Coverage %v of %s report did not match %v", p, format, expect)
+		}
+	}
+}
+
+func TestCoverageText(t *testing.T) {
+	cases := map[float64]string{
+		100:    "100%",
+		99.99:  "99.9%",
+		75:     "75%",
+		33.333: "33.3%",
+	}
+
+	for p, expect := range cases {
+		if text := coverageText(p); text != expect {
+			t.Errorf("Coverage text %q of %v did not match %q", text, p, expect)
+		}
+	}
+}
+
+func TestHttpCoverageUpload(t *testing.T) {
+	upload := func(path, token, report string) (int, string) {
+		headers := map[string]string{}
+		if token != "" {
+			headers["Authorization"] = "Bearer " + token
+		}
+
+		resp, body := doRequest(t, "POST", path, strings.NewReader(report), headers)
+		return resp.Code, body
+	}
+
+	lcov := "SF:a.js\nDA:1,1\nDA:2,1\nDA:3,0\nDA:4,1\nend_of_record\n"
+
+	code, _ := upload("/coverage/upload/luzifer/badge-gen", "s3cr3t", lcov)
+	assert.Equal(t, http.StatusNotFound, code, "uploads should be disabled without token")
+
+	configStore["coverage.luzifer.upload_token"] = "s3cr3t"
+	defer delete(configStore, "coverage.luzifer.upload_token")
+	configStore["coverage.other.upload_token"] = "0th3r"
+	defer delete(configStore, "coverage.other.upload_token")
+
+	code, _ = upload("/coverage/upload/luzifer/badge-gen", "0th3r", lcov)
+	assert.Equal(t, http.StatusUnauthorized, code, "token of other owner")
+
+	code, _ = upload("/coverage/upload/unknown/badge-gen", "s3cr3t", lcov)
+	assert.Equal(t, http.StatusNotFound, code, "owner without token")
+
+	code, _ = upload("/coverage/upload/luzifer/badge-gen", "", lcov)
+	assert.Equal(t, http.StatusUnauthorized, code, "missing token")
+
+	code, _ = upload("/coverage/upload/luzifer/badge-gen", "wrong", lcov)
+	assert.Equal(t, http.StatusUnauthorized, code, "wrong token")
+
+	code, _ = upload("/coverage/upload/luzifer/badge-gen", "s3cr3t", "no report")
+	assert.Equal(t, http.StatusBadRequest, code, "unknown format")
+
+	code, _ = upload("/coverage/upload/luzifer/badge-gen?format=cobertura", "s3cr3t", lcov)
+	assert.Equal(t, http.StatusBadRequest, code, "format should not be detected when given")
+
+	code, body := upload("/coverage/upload/luzifer/badge-gen", "s3cr3t", lcov)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "75%\n", body, "lcov format should be detected")
+
+	code, body = upload("/coverage/upload/luzifer/badge-gen/develop", "s3cr3t", `<coverage line-rate="0.5"></coverage>`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "50%\n", body, "cobertura format should be detected")
+
+	for path, expect := range map[string]string{
+		"/coverage/local/luzifer/badge-gen":         ">75%</text>",
+		"/coverage/local/luzifer/badge-gen/develop": ">50%</text>",
+	} {
+		resp, p := doRequest(t, "GET", path, nil, nil)
+		assert.Equal(t, http.StatusOK, resp.Code, path)
+		assert.Contains(t, p, expect, path)
+	}
+}
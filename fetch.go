@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// fetchStatusError is returned for responses with a non-200 status
+// and carries the headers of the response for the callers to react
+// on (e.g. authentication challenges)
+type fetchStatusError struct {
+	StatusCode int
+	Header     http.Header
+}
+
+func (f fetchStatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d", f.StatusCode)
+}
+
+// fetchJSON executes the request and decodes the JSON response body
+// into out. Responses with a non-200 status are treated as errors.
+// An Accept header already set on the request is kept.
+func fetchJSON(req *http.Request, out interface{}) error {
+	return fetchJSONWith(http.DefaultClient, req, out)
+}
+
+// fetchJSONWith is fetchJSON executing the request using the given
+// client
+func fetchJSONWith(client *http.Client, req *http.Request, out interface{}) error {
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	return fetchDecodedWith(client, req, func(body io.Reader) error {
+		return errors.Wrap(json.NewDecoder(body).Decode(out), "decoding JSON response")
+	})
+}
+
+// fetchJSONHeader is fetchJSON additionally returning the headers of
+// the response (e.g. to follow pagination links)
+func fetchJSONHeader(req *http.Request, out interface{}) (header http.Header, err error) {
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	err = fetchResponseWith(http.DefaultClient, req, func(resp *http.Response) error {
+		header = resp.Header
+		return errors.Wrap(json.NewDecoder(resp.Body).Decode(out), "decoding JSON response")
+	})
+
+	return header, err
+}
+
+// fetchXML executes the request and decodes the XML response body
+// into out. Responses with a non-200 status are treated as errors.
+func fetchXML(req *http.Request, out interface{}) error {
+	return fetchDecoded(req, func(body io.Reader) error {
+		return errors.Wrap(xml.NewDecoder(body).Decode(out), "decoding XML response")
+	})
+}
+
+func fetchDecoded(req *http.Request, decode func(io.Reader) error) error {
+	return fetchDecodedWith(http.DefaultClient, req, decode)
+}
+
+// fetchDecodedWith executes the request using the given client and
+// passes the body of successful responses to decode
+func fetchDecodedWith(client *http.Client, req *http.Request, decode func(io.Reader) error) error {
+	return fetchResponseWith(client, req, func(resp *http.Response) error {
+		return decode(resp.Body)
+	})
+}
+
+// fetchResponseWith executes the request using the given client and
+// passes successful responses to handle
+func fetchResponseWith(client *http.Client, req *http.Request, handle func(*http.Response) error) error {
+	req.Header.Set("User-Agent", "badge-gen/"+version)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "executing HTTP request")
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logrus.WithError(err).Error("closing response body (leaked fd)")
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fetchStatusError{StatusCode: resp.StatusCode, Header: resp.Header}
+	}
+
+	return handle(resp)
+}
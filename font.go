@@ -1,40 +1,319 @@
-// Some of this code (namely the code for computing the
-// width of a string in a given font) was copied from
-// code.google.com/p/freetype-go/freetype/ which includes
-// the following copyright notice:
-// Copyright 2010 The Freetype-Go Authors. All rights reserved.
 package main
 
 import (
-	"github.com/golang/freetype/truetype"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
 	"github.com/pkg/errors"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
 	"golang.org/x/image/math/fixed"
 )
 
 const (
-	fontSize = 11
+	// #configStore font.fallback - []string - Paths of TTF / OTF fonts used to measure characters missing from the embedded DejaVu Sans (e.g. CJK), their family names are added to the font-family of the badges
+	configKeyFontFallback = "font.fallback"
+	// #configStore font.<name>.path - string - Path of a TTF / OTF font selectable as <name> using the font parameter of the badges
+	configKeyFontPath = "font.%s.path"
+)
+
+const (
+	fontSize    = 11
+	fontSizeMin = 8
+	fontSizeMax = 32
+
+	// fontFamiliesGeneric is appended to the families of the fonts
+	// used to measure the text
+	fontFamiliesGeneric = "Verdana,Geneva,sans-serif"
+
+	textDirectionLTR = "ltr"
+	textDirectionRTL = "rtl"
+)
+
+const (
+	runeZWJ                    = '\u200d'
+	runeEmojiPresentation      = '\ufe0f'
+	runeRegionalIndicatorFirst = '\U0001f1e6'
+	runeRegionalIndicatorLast  = '\U0001f1ff'
+	runeEmojiModifierFirst     = '\U0001f3fb'
+	runeEmojiModifierLast      = '\U0001f3ff'
 )
 
+var (
+	fontChain     []*badgeFont
+	fontChainErr  error
+	fontChainOnce sync.Once
+
+	customFonts     = map[string]*badgeFont{}
+	customFontsLock sync.Mutex
+
+	// emojiRanges contain the code points rendered as (usually wide)
+	// emoji by the browsers
+	emojiRanges = &unicode.RangeTable{
+		R16: []unicode.Range16{
+			{Lo: 0x2600, Hi: 0x27bf, Stride: 1},
+			{Lo: 0x2b00, Hi: 0x2bff, Stride: 1},
+		},
+		R32: []unicode.Range32{
+			{Lo: 0x1f000, Hi: 0x1faff, Stride: 1},
+		},
+	}
+
+	// wideRanges contain scripts rendered with full-width glyphs
+	wideRanges = []*unicode.RangeTable{
+		unicode.Han, unicode.Hangul, unicode.Hiragana, unicode.Katakana, unicode.Bopomofo,
+		{R16: []unicode.Range16{
+			{Lo: 0x3000, Hi: 0x303f, Stride: 1},
+			{Lo: 0xff00, Hi: 0xff60, Stride: 1},
+			{Lo: 0xffe0, Hi: 0xffe6, Stride: 1},
+		}},
+	}
+
+	rtlRanges = []*unicode.RangeTable{
+		unicode.Arabic, unicode.Hebrew, unicode.Nko, unicode.Syriac, unicode.Thaana,
+	}
+)
+
+// badgeFont wraps a parsed TTF / OTF font to measure texts in em
+type badgeFont struct {
+	Family string
+
+	font *sfnt.Font
+	upem fixed.Int26_6
+}
+
+func parseBadgeFont(data []byte) (*badgeFont, error) {
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing font")
+	}
+
+	family, err := f.Name(nil, sfnt.NameIDFamily)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading font family")
+	}
+
+	return &badgeFont{Family: family, font: f, upem: fixed.Int26_6(f.UnitsPerEm())}, nil
+}
+
+// glyph returns the index of the glyph for the rune or 0 when the
+// font has no glyph for it
+func (b *badgeFont) glyph(buf *sfnt.Buffer, r rune) sfnt.GlyphIndex {
+	idx, err := b.font.GlyphIndex(buf, r)
+	if err != nil {
+		return 0
+	}
+	return idx
+}
+
+// advance returns the advance width of the glyph in em
+func (b *badgeFont) advance(buf *sfnt.Buffer, idx sfnt.GlyphIndex) float64 {
+	// Requesting the metrics with ppem set to the units per em returns
+	// them in font units
+	adv, err := b.font.GlyphAdvance(buf, idx, b.upem, font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return float64(adv) / float64(b.upem)
+}
+
+// kern returns the kerning between the glyphs in em
+func (b *badgeFont) kern(buf *sfnt.Buffer, left, right sfnt.GlyphIndex) float64 {
+	k, err := b.font.Kern(buf, left, right, b.upem, font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return float64(k) / float64(b.upem)
+}
+
+// loadFontChain parses the embedded font followed by the configured
+// fallback fonts, glyphs are taken from the first font having them
+func loadFontChain() ([]*badgeFont, error) {
+	fontChainOnce.Do(func() {
+		binFont, _ := assets.ReadFile("assets/DejaVuSans.ttf")
+		f, err := parseBadgeFont(binFont)
+		if err != nil {
+			fontChainErr = errors.Wrap(err, "loading embedded font")
+			return
+		}
+		fontChain = append(fontChain, f)
+
+		for _, path := range configStore.StrSlice(configKeyFontFallback) {
+			if f, err = loadFontFile(path); err != nil {
+				fontChainErr = errors.Wrap(err, "loading fallback font")
+				return
+			}
+			fontChain = append(fontChain, f)
+		}
+	})
+
+	return fontChain, fontChainErr
+}
+
+// fontChainFor returns the font chain to measure texts rendered with
+// the given custom font. The custom font takes precedence over the
+// default chain which is used alone for an empty name.
+func fontChainFor(name string) ([]*badgeFont, error) {
+	chain, err := loadFontChain()
+	if err != nil || name == "" {
+		return chain, err
+	}
+
+	customFontsLock.Lock()
+	defer customFontsLock.Unlock()
+
+	f, ok := customFonts[name]
+	if !ok {
+		path := configStore.Str(fmt.Sprintf(configKeyFontPath, name))
+		if path == "" {
+			return nil, errors.Errorf("font %q is not registered", name)
+		}
+
+		if f, err = loadFontFile(path); err != nil {
+			return nil, errors.Wrapf(err, "loading font %q", name)
+		}
+		customFonts[name] = f
+	}
+
+	return append([]*badgeFont{f}, chain...), nil
+}
+
+func loadFontFile(path string) (*badgeFont, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // Path is taken from the operator supplied config
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading font %q", path)
+	}
+
+	f, err := parseBadgeFont(data)
+	return f, errors.Wrapf(err, "font %q", path)
+}
+
+// fontFamilies returns the font-family to render the text with
+// listing the families of the fonts used to measure it
+func fontFamilies(fonts []*badgeFont) string {
+	families := make([]string, 0, len(fonts)+1)
+	for _, f := range fonts {
+		if f.Family != "" {
+			families = append(families, f.Family)
+		}
+	}
+
+	return strings.Join(append(families, fontFamiliesGeneric), ",")
+}
+
+// textDirection returns the direction of the text determined by its
+// first letter
+func textDirection(text string) string {
+	for _, r := range text {
+		switch {
+		case unicode.IsOneOf(rtlRanges, r):
+			return textDirectionRTL
+		case unicode.IsLetter(r):
+			return textDirectionLTR
+		}
+	}
+	return textDirectionLTR
+}
+
+// calculateTextWidth measures the text using the default fonts and
+// font size
 func calculateTextWidth(text string) (int, error) {
-	binFont, _ := assets.ReadFile("assets/DejaVuSans.ttf")
-	font, err := truetype.Parse(binFont)
+	fonts, err := loadFontChain()
 	if err != nil {
-		return 0, errors.Wrap(err, "parsing truetype font")
+		return 0, err
+	}
+
+	return measureText(text, fonts, fontSize), nil
+}
+
+// measureText returns the width of the text in pixels rendered with
+// the glyphs of the first font of the chain having them
+func measureText(text string, fonts []*badgeFont, size int) int {
+	var (
+		buf      sfnt.Buffer
+		runes    = []rune(text)
+		rtl      = textDirection(text) == textDirectionRTL
+		width    float64
+		prev     sfnt.GlyphIndex
+		prevFont *badgeFont
+	)
+
+	for i, r := range runes {
+		f, index := fontForRune(&buf, fonts, r)
+
+		var advance float64
+		switch {
+		case isZeroWidthRune(runes, i):
+			prevFont = nil
+			continue
+
+		case index == 0 && (unicode.Is(emojiRanges, r) || unicode.IsOneOf(wideRanges, r)):
+			// Missing emoji and full-width characters take one em in
+			// the font of the browser rendering the badge
+			advance = 1
+
+		default:
+			advance = f.advance(&buf, index)
+			switch {
+			case prevFont != f:
+				// No kerning between glyphs of different fonts
+			case rtl:
+				// Kerning is applied to the glyphs in the order
+				// they are displayed in
+				advance += f.kern(&buf, index, prev)
+			default:
+				advance += f.kern(&buf, prev, index)
+			}
+		}
+
+		if i+1 < len(runes) && runes[i+1] == runeEmojiPresentation && advance < 1 {
+			// The character is rendered as emoji instead of its
+			// text glyph
+			advance = 1
+		}
+
+		width += advance
+		prev, prevFont = index, f
 	}
 
-	scale := fontSize / float64(font.FUnitsPerEm())
+	return int(width * float64(size))
+}
+
+// fontForRune returns the first font of the chain having a glyph for
+// the rune or the primary font if none has
+func fontForRune(buf *sfnt.Buffer, fonts []*badgeFont, r rune) (*badgeFont, sfnt.GlyphIndex) {
+	for _, f := range fonts {
+		if index := f.glyph(buf, r); index != 0 {
+			return f, index
+		}
+	}
+	return fonts[0], 0
+}
+
+// isZeroWidthRune reports whether the rune at the position does not
+// add to the width of the text as it is a format character, modifies
+// the previous emoji or is joined into it
+func isZeroWidthRune(runes []rune, i int) bool {
+	r := runes[i]
 
-	width := 0
-	prev, hasPrev := truetype.Index(0), false
-	for _, rune := range text {
-		fUnitsPerEm := fixed.Int26_6(font.FUnitsPerEm())
-		index := font.Index(rune)
-		if hasPrev {
-			width += int(font.Kern(fUnitsPerEm, prev, index))
+	switch {
+	case unicode.In(r, unicode.Cf, unicode.Mn, unicode.Me):
+		return true
+	case r >= runeEmojiModifierFirst && r <= runeEmojiModifierLast:
+		return i > 0
+	case i > 0 && runes[i-1] == runeZWJ:
+		return true
+	case r >= runeRegionalIndicatorFirst && r <= runeRegionalIndicatorLast:
+		// Pairs of regional indicators form a single flag
+		n := 0
+		for j := i - 1; j >= 0 && runes[j] >= runeRegionalIndicatorFirst && runes[j] <= runeRegionalIndicatorLast; j-- {
+			n++
 		}
-		width += int(font.HMetric(fUnitsPerEm, index).AdvanceWidth)
-		prev, hasPrev = index, true
+		return n%2 == 1
 	}
 
-	return int(float64(width) * scale), nil
+	return false
 }
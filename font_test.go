@@ -29,3 +29,37 @@ func TestStringLength(t *testing.T) {
 		t.Errorf("Text length changed and is now %d", w)
 	}
 }
+
+func TestStringLengthWideCharacters(t *testing.T) {
+	cases := map[string]int{
+		"中文":    22,
+		"😀😀":    22,
+		"👍🏽":    11,
+		"🇩🇪":    11,
+		"👨‍👩‍👧": 11,
+		"☀":     9,
+		"☀️":    11,
+	}
+
+	for in, expect := range cases {
+		if w, err := calculateTextWidth(in); err != nil || w != expect {
+			t.Errorf("Text length of %q was %d (%v), expected %d", in, w, err, expect)
+		}
+	}
+}
+
+func TestTextDirection(t *testing.T) {
+	cases := map[string]string{
+		"build":       textDirectionLTR,
+		"שלום":        textDirectionRTL,
+		"123 مرحبا":   textDirectionRTL,
+		"v1.2 - שלום": textDirectionLTR,
+		"":            textDirectionLTR,
+	}
+
+	for in, expect := range cases {
+		if d := textDirection(in); d != expect {
+			t.Errorf("Direction of %q was %q, expected %q", in, d, expect)
+		}
+	}
+}
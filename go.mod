@@ -5,13 +5,13 @@ go 1.21
 require (
 	github.com/Luzifer/go_helpers/v2 v2.20.1
 	github.com/Luzifer/rconfig/v2 v2.4.0
-	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
 	github.com/gorilla/mux v1.8.0
 	github.com/pkg/errors v0.9.1
 	github.com/sirupsen/logrus v1.9.3
 	github.com/stretchr/testify v1.8.4
 	github.com/tdewolff/minify v2.3.6+incompatible
 	golang.org/x/image v0.13.0
+	golang.org/x/mod v0.14.0
 	golang.org/x/net v0.17.0
 	gopkg.in/yaml.v2 v2.4.0
 )
@@ -23,6 +23,7 @@ require (
 	github.com/tdewolff/parse v2.3.4+incompatible // indirect
 	github.com/tdewolff/test v1.0.6 // indirect
 	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
 	gopkg.in/validator.v2 v2.0.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
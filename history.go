@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// historyResolution is the size of the buckets the points are
+	// recorded in, values recorded within the same bucket replace the
+	// latest point
+	historyResolution = time.Hour
+	historyRetention  = 31 * 24 * time.Hour
+	historySparkWidth = 16
+)
+
+var (
+	historyLock sync.Mutex
+
+	historyNumber = regexp.MustCompile(`^([+-]?\d+(?:\.\d+)?)\s?([kMGT])?(?:$|[^\d.:-])`)
+
+	historyPeriods = map[string]time.Duration{
+		"day":   24 * time.Hour,
+		"week":  7 * 24 * time.Hour,
+		"month": 30 * 24 * time.Hour,
+	}
+
+	historySparkLevels = []rune("▁▂▃▄▅▆▇█")
+)
+
+type historyPoint struct {
+	Time  int64   `json:"t"`
+	Value float64 `json:"v"`
+}
+
+// parseBadgeNumber extracts the numeric value from the start of a badge
+// text including the metric suffixes used by metricFormat and
+// byteFormat ("1.2k", "12 votes", "28.5 MB"). Texts like versions
+// or dates are not considered numeric.
+func parseBadgeNumber(text string) (float64, bool) {
+	m := historyNumber.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return 0, false
+	}
+
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+
+	switch m[2] {
+	case "k":
+		v *= 1e3
+	case "M":
+		v *= 1e6
+	case "G":
+		v *= 1e9
+	case "T":
+		v *= 1e12
+	}
+
+	return v, true
+}
+
+func historyKey(service string, params []string) string {
+	return service + "/" + strings.Join(params, "/")
+}
+
+// recordHistory adds the value to the history stored for the key and
+// returns the updated history
+func recordHistory(key string, value float64, now time.Time) []historyPoint {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+
+	bucket := now.Truncate(historyResolution).Unix()
+
+	points := loadHistory(key)
+	if l := len(points); l > 0 && points[l-1].Time >= bucket {
+		points = points[:l-1]
+	}
+	points = append(points, historyPoint{Time: bucket, Value: value})
+
+	cutoff := now.Add(-historyRetention).Unix()
+	for len(points) > 0 && points[0].Time < cutoff {
+		points = points[1:]
+	}
+
+	data, err := json.Marshal(points)
+	if err != nil {
+		logErr(err, "encoding history")
+		return points
+	}
+	logErr(cacheStore.Set("history", key, string(data), historyRetention), "writing history to cache")
+
+	return points
+}
+
+func loadHistory(key string) []historyPoint {
+	var points []historyPoint
+
+	data, err := cacheStore.Get("history", key)
+	if err != nil {
+		return nil
+	}
+
+	logErr(json.Unmarshal([]byte(data), &points), "decoding history")
+	return points
+}
+
+// historySince returns the points recorded within the period
+func historySince(points []historyPoint, since time.Time) []historyPoint {
+	for i, p := range points {
+		if p.Time >= since.Unix() {
+			return points[i:]
+		}
+	}
+	return nil
+}
+
+// historyDelta formats the change between the first and the last
+// point in a compact form ("+12", "-1.5k", "±0")
+func historyDelta(points []historyPoint) string {
+	if len(points) < 2 { //nolint:gomnd
+		return "±0"
+	}
+
+	delta := points[len(points)-1].Value - points[0].Value
+
+	var text string
+	switch {
+	case math.Abs(delta) >= 1000 && delta == math.Trunc(delta): //nolint:gomnd
+		text = metricFormat(int64(math.Abs(delta)))
+	default:
+		text = strconv.FormatFloat(math.Round(math.Abs(delta)*100)/100, 'f', -1, 64) //nolint:gomnd
+	}
+
+	switch {
+	case delta > 0:
+		return "+" + text
+	case delta < 0:
+		return "-" + text
+	default:
+		return "±0"
+	}
+}
+
+// historyTrend returns an arrow indicating the direction of the change
+func historyTrend(points []historyPoint) string {
+	if len(points) < 2 { //nolint:gomnd
+		return "→"
+	}
+
+	switch first, last := points[0].Value, points[len(points)-1].Value; {
+	case last > first:
+		return "↑"
+	case last < first:
+		return "↓"
+	default:
+		return "→"
+	}
+}
+
+// historySparkline renders the points as a line of block characters,
+// sampling them down to a fixed number of characters
+func historySparkline(points []historyPoint) string {
+	if len(points) > historySparkWidth {
+		sampled := make([]historyPoint, historySparkWidth)
+		for i := range sampled {
+			sampled[i] = points[i*(len(points)-1)/(historySparkWidth-1)]
+		}
+		points = sampled
+	}
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		lo, hi = math.Min(lo, p.Value), math.Max(hi, p.Value)
+	}
+
+	var out strings.Builder
+	for _, p := range points {
+		level := 0
+		if hi > lo {
+			level = int(math.Round((p.Value - lo) / (hi - lo) * float64(len(historySparkLevels)-1)))
+		}
+		out.WriteRune(historySparkLevels[level])
+	}
+
+	return out.String()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBadgeNumber(t *testing.T) {
+	cases := map[string]float64{
+		"12 votes": 12,
+		"1k":       1000,
+		"6M":       6e6,
+		"28.5 MB":  28.5e6,
+		"75%":      75,
+		"-3":       -3,
+	}
+
+	for text, expect := range cases {
+		if v, ok := parseBadgeNumber(text); !ok || v != expect {
+			t.Errorf("Parsed number %v (%v) of %q did not match %v", v, ok, text, expect)
+		}
+	}
+
+	for _, text := range []string{"1.2.3", "v1.2", "2024-01-01", "passing", "12:30"} {
+		if v, ok := parseBadgeNumber(text); ok {
+			t.Errorf("Text %q was parsed as number %v", text, v)
+		}
+	}
+}
+
+func TestHistoryDelta(t *testing.T) {
+	cases := []struct {
+		Points []historyPoint
+		Delta  string
+		Trend  string
+		Spark  string
+	}{
+		{[]historyPoint{{Value: 10}, {Value: 22}}, "+12", "↑", "▁█"},
+		{[]historyPoint{{Value: 5000}, {Value: 2000}}, "-3k", "↓", "█▁"},
+		{[]historyPoint{{Value: 1.5}, {Value: 1.5}, {Value: 1.5}}, "±0", "→", "▁▁▁"},
+		{[]historyPoint{{Value: 1}, {Value: 3}, {Value: 5}}, "+4", "↑", "▁▅█"},
+	}
+
+	for _, c := range cases {
+		if d := historyDelta(c.Points); d != c.Delta {
+			t.Errorf("Delta %q of %v did not match %q", d, c.Points, c.Delta)
+		}
+		if tr := historyTrend(c.Points); tr != c.Trend {
+			t.Errorf("Trend %q of %v did not match %q", tr, c.Points, c.Trend)
+		}
+		if s := historySparkline(c.Points); s != c.Spark {
+			t.Errorf("Sparkline %q of %v did not match %q", s, c.Points, c.Spark)
+		}
+	}
+}
+
+func TestRecordHistory(t *testing.T) {
+	base := time.Now().Truncate(historyResolution)
+
+	// Renders every 30 minutes keep one point per hour
+	var points []historyPoint
+	for i, offset := range []time.Duration{-3 * time.Hour, -150 * time.Minute, -2 * time.Hour, -90 * time.Minute, -time.Hour, -30 * time.Minute, 0} {
+		points = recordHistory("test/history", float64(i), base.Add(offset))
+	}
+
+	expect := []historyPoint{
+		{Time: base.Add(-3 * time.Hour).Unix(), Value: 1},
+		{Time: base.Add(-2 * time.Hour).Unix(), Value: 3},
+		{Time: base.Add(-time.Hour).Unix(), Value: 5},
+		{Time: base.Unix(), Value: 6},
+	}
+
+	if len(points) != len(expect) {
+		t.Fatalf("Unexpected history %v", points)
+	}
+	for i := range expect {
+		if points[i] != expect[i] {
+			t.Errorf("Point %v did not match %v", points[i], expect[i])
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var licenseWhitespace = regexp.MustCompile(`\s+`)
+
+// licenseSignatures identify well known licenses by phrases which
+// must all be contained in the (whitespace normalized, lowercased)
+// license text. More specific licenses must come first as for
+// example the BSD-3-Clause contains the BSD-2-Clause phrases.
+var licenseSignatures = []struct {
+	SPDX    string
+	Phrases []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license", "version 3"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"MIT", []string{"permission is hereby granted, free of charge", "the above copyright notice and this permission notice shall be included"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose with or without fee is hereby granted"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"CC0-1.0", []string{"cc0 1.0 universal"}},
+	{"WTFPL", []string{"do what the fuck you want to public license"}},
+}
+
+// detectLicense tries to identify the license contained in the given
+// text and returns its SPDX identifier or an empty string if the
+// license is unknown
+func detectLicense(text string) string {
+	text = licenseWhitespace.ReplaceAllString(strings.ToLower(text), " ")
+
+	for _, sig := range licenseSignatures {
+		matches := true
+		for _, p := range sig.Phrases {
+			if !strings.Contains(text, p) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			return sig.SPDX
+		}
+	}
+
+	return ""
+}
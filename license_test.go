@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestDetectLicense(t *testing.T) {
+	cases := map[string]string{
+		`Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software ... The above copyright notice and this
+permission notice shall be included in all copies or substantial portions of the Software.`: "MIT",
+		`                                 Apache License
+                           Version 2.0, January 2004`: "Apache-2.0",
+		`Redistribution and use in source and binary forms, with or without
+modification, are permitted ... Neither the name of Google Inc. nor the names of its
+contributors may be used`: "BSD-3-Clause",
+		`Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met`: "BSD-2-Clause",
+		`GNU GENERAL PUBLIC LICENSE
+                       Version 3, 29 June 2007`: "GPL-3.0",
+		`Some custom license text`: "",
+	}
+
+	for text, expect := range cases {
+		if l := detectLicense(text); l != expect {
+			t.Errorf("Detected license %q did not match %q", l, expect)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// badgeLinkSchemes contains the URL schemes allowed as link targets of
+// badges to prevent embedding javascript: or data: URLs into the SVG
+var badgeLinkSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// validBadgeLink reports whether the link is an absolute URL using one
+// of the allowed schemes
+func validBadgeLink(link string) bool {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return false
+	}
+
+	if !badgeLinkSchemes[strings.ToLower(u.Scheme)] {
+		return false
+	}
+
+	return u.Host != "" || u.Opaque != ""
+}
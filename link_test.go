@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestValidBadgeLink(t *testing.T) {
+	for link, exp := range map[string]bool{
+		"https://github.com/Luzifer/badge-gen": true,
+		"http://example.com/?a=1&b=2":          true,
+		"mailto:badges@example.com":            true,
+		"HTTPS://example.com":                  true,
+		"javascript:alert(1)":                  false,
+		"data:text/html,<script></script>":     false,
+		"/relative/path":                       false,
+		"https://":                             false,
+		"ftp://example.com/":                   false,
+	} {
+		if res := validBadgeLink(link); res != exp {
+			t.Errorf("validBadgeLink(%q) = %v, expected %v", link, res, exp)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/Luzifer/badge-gen/cache"
@@ -13,20 +14,42 @@ import (
 )
 
 func testGenerateMux() *mux.Router {
-	m := mux.NewRouter()
+	m := mux.NewRouter().UseEncodedPath().SkipClean(true)
 	m.HandleFunc("/v1/badge", generateBadge).Methods("GET")
+	m.HandleFunc("/v1/badge", generateBadgeFromJSON).Methods("POST")
+	m.HandleFunc("/coverage/upload/{parameters:.*}", handleCoverageUpload).Methods("POST")
+	m.HandleFunc("/push/{namespace}/{key}", handlePush).Methods("PUT")
 	m.HandleFunc("/{service}/{parameters:.*}", generateServiceBadge).Methods("GET")
 	m.HandleFunc("/", handleDemoPage)
 	return m
 }
 
+// doRequest executes the request against the test mux and returns
+// the recorded response along with its body
+func doRequest(t *testing.T, method, path string, body io.Reader, headers map[string]string) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, path, body) //nolint:noctx // fine for an internal test
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp := httptest.NewRecorder()
+	testGenerateMux().ServeHTTP(resp, req)
+
+	return resp, resp.Body.String()
+}
+
 func TestMain(m *testing.M) {
 	cacheStore = cache.NewInMemCache()
 	os.Exit(m.Run())
 }
 
 func TestCreateBadge(t *testing.T) {
-	badgeData, _ := createBadge("API", "Documentation", "4c1")
+	badgeData, _ := createBadge("API", "Documentation", "4c1", defaultLabelColor)
 	badge := string(badgeData)
 
 	assert.Contains(t, badge, ">API</text>")
@@ -35,59 +58,222 @@ func TestCreateBadge(t *testing.T) {
 }
 
 func TestHttpResponseMissingParameters(t *testing.T) {
-	resp := httptest.NewRecorder()
+	resp, p := doRequest(t, "GET", "/v1/badge", nil, nil)
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Contains(t, p, "You must specify parameters 'title' and 'text'.")
+}
 
-	req, err := http.NewRequest("GET", "/v1/badge", nil)
-	if err != nil {
-		t.Fatal(err)
+func TestHttpResponseWithoutColor(t *testing.T) {
+	resp, p := doRequest(t, "GET", "/static/API/Documentation", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "image/svg+xml", resp.Header().Get("Content-Type"))
+	// Check whether there is a SVG in the response, format checks are in other checks
+	assert.Contains(t, p, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"133\" height=\"20\" role=\"img\" aria-label=\"API: Documentation\">")
+	assert.Contains(t, p, "#4c1", "default color should be set")
+}
+
+func TestHttpResponseWithColor(t *testing.T) {
+	resp, p := doRequest(t, "GET", "/static/API/Documentation/572", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "image/svg+xml", resp.Header().Get("Content-Type"))
+	// Check whether there is a SVG in the response, format checks are in other checks
+	assert.Contains(t, p, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"133\" height=\"20\" role=\"img\" aria-label=\"API: Documentation\">")
+	assert.NotContains(t, p, "#4c1", "default color should not be set")
+	assert.Contains(t, p, "#572", "given color should be set")
+}
+
+func TestHttpResponseWithOverrides(t *testing.T) {
+	resp, p := doRequest(t, "GET", "/static/API/Documentation/572?label=Docs&color=blue&labelColor=red&prefix=v&suffix=!", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, p, ">Docs</text>", "label should be overridden")
+	assert.Contains(t, p, ">vDocumentation!</text>", "prefix and suffix should be added")
+	assert.Contains(t, p, "#007ec6", "color should be overridden")
+	assert.Contains(t, p, "#e05d44", "label color should be overridden")
+	assert.NotContains(t, p, "#572", "handler color should not be set")
+}
+
+func TestHttpResponseWithOverridesSingleSegment(t *testing.T) {
+	resp, p := doRequest(t, "GET", "/static//sponsored/ea4aaa?label=Docs&labelColor=red&color=blue&prefix=v&suffix=!", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 2, strings.Count(p, "</text>"), "no label should be added")
+	assert.Contains(t, p, ">vsponsored!</text>", "prefix and suffix should be added to the text")
+	assert.NotContains(t, p, ">Docs</text>", "label should not be added")
+	assert.NotContains(t, p, "#e05d44", "label color should not be applied")
+	assert.Contains(t, p, "#007ec6", "color should be overridden")
+
+	resp, p = doRequest(t, "GET", "/static/deprecated//red?label=obsolete&prefix=v&suffix=!&color=blue&rightLink=https%3A%2F%2Fexample.com", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 2, strings.Count(p, "</text>"), "no text should be added")
+	assert.Contains(t, p, ">obsolete</text>", "label should be overridden")
+	assert.NotContains(t, p, "v</text>", "prefix should not be added to the label")
+	assert.NotContains(t, p, "!</text>", "suffix should not be added to the label")
+	assert.Contains(t, p, "#e05d44", "label should keep its color")
+	assert.NotContains(t, p, "<a ", "hidden text should not be linked")
+}
+
+func TestHttpResponseWithOverridesTwoSegments(t *testing.T) {
+	resp, p := doRequest(t, "GET", "/static/API/Documentation/572?prefix=v&suffix=!&link=https%3A%2F%2Fexample.com", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, p, ">API</text>", "label should not be changed")
+	assert.Contains(t, p, ">vDocumentation!</text>", "prefix and suffix should be added")
+	assert.Equal(t, 2, strings.Count(p, `<a target="_blank" href="https://example.com"`), "link should be set on both segments")
+}
+
+func TestHttpResponseWithOverridesMultiSegment(t *testing.T) {
+	resp, p := doRequest(t, "GET", "/static/build/linux/4c1/macos/4c1/windows/e05d44?label=ci&labelColor=blue&color=572&prefix=%5B&suffix=%5D", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	for _, text := range []string{">ci</text>", ">[linux</text>", ">macos</text>", ">windows]</text>"} {
+		assert.Contains(t, p, text)
 	}
+	assert.Contains(t, p, "#007ec6", "label color should be overridden")
+	assert.Equal(t, 3, strings.Count(p, `fill="#572"`), "color should be set on all text segments")
+	assert.NotContains(t, p, "#e05d44", "handler color should not be set")
+}
 
-	testGenerateMux().ServeHTTP(resp, req)
-	if p, err := io.ReadAll(resp.Body); err != nil {
-		t.Fail()
-	} else {
-		assert.Equal(t, http.StatusInternalServerError, resp.Code)
-		assert.Contains(t, string(p), "You must specify parameters 'title' and 'text'.")
+func TestHttpResponseWithInvalidColor(t *testing.T) {
+	resp, p := doRequest(t, "GET", "/static/API/Documentation/%22%3E%3Cscript%3E", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, p, ">invalid color</text>")
+	assert.NotContains(t, p, "<script>", "invalid color must not be rendered")
+}
+
+func TestHttpResponseMultiSegment(t *testing.T) {
+	resp, p := doRequest(t, "GET", "/static/build/linux/4c1/macos/4c1/windows/e05d44", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	for _, text := range []string{">build</text>", ">linux</text>", ">macos</text>", ">windows</text>"} {
+		assert.Contains(t, p, text)
 	}
+	assert.Contains(t, p, "#e05d44", "color of last segment should be set")
 }
 
-func TestHttpResponseWithoutColor(t *testing.T) {
-	resp := httptest.NewRecorder()
+func TestHttpResponseJSON(t *testing.T) {
+	resp, p := doRequest(t, "POST", "/v1/badge", strings.NewReader(`{"segments":[{"text":"API"},{"text":"Documentation"},{"text":"v2","color":"blue"}]}`), nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "image/svg+xml", resp.Header().Get("Content-Type"))
+	assert.Contains(t, p, ">v2</text>")
+	assert.Contains(t, p, "#4c1", "default color should be set")
+	assert.Contains(t, p, "#007ec6", "given color should be set")
+}
 
-	req, err := http.NewRequest("GET", "/static/API/Documentation", nil)
-	if err != nil {
-		t.Fatal(err)
+func TestCreateSingleSegmentBadge(t *testing.T) {
+	single, _ := createBadge("", "sponsored", "ea4aaa", defaultLabelColor)
+	double, _ := createBadge("API", "sponsored", "ea4aaa", defaultLabelColor)
+
+	assert.Contains(t, string(single), ">sponsored</text>")
+	assert.NotContains(t, string(single), "#555", "empty label must not be rendered")
+	assert.Contains(t, string(single), "M0 0 h", "text segment should start at the left edge")
+	assert.Less(t, len(single), len(double))
+}
+
+func TestHttpResponseSingleSegment(t *testing.T) {
+	for path, color := range map[string]string{
+		"/static//sponsored/ea4aaa": "#ea4aaa",
+		"/static/sponsored":         "#4c1",
+		"/static/deprecated//red":   "#e05d44",
+	} {
+		resp, p := doRequest(t, "GET", path, nil, nil)
+		assert.Equal(t, http.StatusOK, resp.Code, path)
+		assert.Equal(t, 2, strings.Count(p, "</text>"), "%s should have one segment", path)
+		assert.Contains(t, p, color, path)
+		assert.NotContains(t, p, "#555", "%s should not have a label", path)
 	}
+}
 
-	testGenerateMux().ServeHTTP(resp, req)
-	if p, err := io.ReadAll(resp.Body); err != nil {
-		t.Fail()
-	} else {
-		assert.Equal(t, http.StatusOK, resp.Code)
-		assert.Equal(t, "image/svg+xml", resp.Header().Get("Content-Type"))
-		// Check whether there is a SVG in the response, format checks are in other checks
-		assert.Contains(t, string(p), "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"133\" height=\"20\">")
-		assert.Contains(t, string(p), "#4c1", "default color should be set")
+func TestHttpResponseWithoutText(t *testing.T) {
+	resp, _ := doRequest(t, "GET", "/v1/badge?title=deprecated&color=red", nil, nil)
+	assert.Equal(t, http.StatusMovedPermanently, resp.Code)
+	assert.Equal(t, "/static/deprecated//red", resp.Header().Get("Location"))
+}
+
+func TestHttpResponseWithLinks(t *testing.T) {
+	resp, p := doRequest(t, "GET", "/static/%3Cb%3E/Documentation?leftLink=https%3A%2F%2Fexample.com%2F%3Fa%3D1%26b%3D2&rightLink=https%3A%2F%2Fexample.com%2Fdocs", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, p, "&lt;b&gt;</text>", "text should be escaped")
+	assert.Contains(t, p, `href="https://example.com/?a=1&amp;b=2"`, "link should be escaped")
+	assert.Contains(t, p, `href="https://example.com/docs"`)
+}
+
+func TestHttpResponseWithInvalidLink(t *testing.T) {
+	resp, p := doRequest(t, "GET", "/static/API/Documentation?link=javascript%3Aalert(1)", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, p, ">invalid link</text>")
+	assert.NotContains(t, p, "javascript:", "invalid link must not be rendered")
+}
+
+func TestHttpResponseAccessibleTitle(t *testing.T) {
+	for path, title := range map[string]string{
+		"/static/build/linux/4c1/windows/e05d44":         "build: linux, windows",
+		"/static//sponsored/ea4aaa":                      "sponsored",
+		"/static/API/Documentation?alt=API%20%26%20docs": "API &amp; docs",
+	} {
+		_, p := doRequest(t, "GET", path, nil, nil)
+		assert.Contains(t, p, `role="img" aria-label="`+title+`"`, path)
+		assert.Contains(t, p, "<title>"+title+"</title>", path)
 	}
 }
 
-func TestHttpResponseWithColor(t *testing.T) {
-	resp := httptest.NewRecorder()
+func TestHttpResponseWithTheme(t *testing.T) {
+	for theme, check := range map[string]func(string){
+		"dark": func(p string) {
+			assert.Contains(t, p, "#30363d", "dark label color should be set")
+			assert.NotContains(t, p, "#555", "light label color should not be set")
+			assert.NotContains(t, p, "prefers-color-scheme")
+		},
+		"auto": func(p string) {
+			assert.Contains(t, p, "#555", "light label color should be set")
+			assert.Contains(t, p, "@media(prefers-color-scheme:dark)")
+			assert.Contains(t, p, `class="s0"`)
+		},
+		"light": func(p string) {
+			assert.Contains(t, p, "#555", "light label color should be set")
+			assert.NotContains(t, p, "prefers-color-scheme")
+		},
+		"neon": func(p string) {
+			assert.Contains(t, p, ">invalid theme</text>")
+		},
+	} {
+		resp, p := doRequest(t, "GET", "/static/API/Documentation?theme="+theme, nil, nil)
+		assert.Equal(t, http.StatusOK, resp.Code, theme)
+		check(p)
+	}
+}
+
+func TestHttpResponseRightToLeft(t *testing.T) {
+	resp, p := doRequest(t, "GET", "/static/build/%D7%A2%D7%95%D7%91%D7%A8", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 2, strings.Count(p, `direction="rtl"`), "only the hebrew text should be rtl")
+}
 
-	req, err := http.NewRequest("GET", "/static/API/Documentation/572", nil) //nolint:noctx // fine for an internal test
+func TestHttpResponseWithFontSize(t *testing.T) {
+	resp, p := doRequest(t, "GET", "/static/API/Documentation?size=22", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, p, `height="40"`, "height should be scaled")
+	assert.Contains(t, p, `font-size="22"`)
+	assert.Contains(t, p, `y="28"`, "baseline should be scaled")
+}
+
+func TestHttpResponseWithCustomFont(t *testing.T) {
+	font, err := assets.ReadFile("assets/DejaVuSans.ttf")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	testGenerateMux().ServeHTTP(resp, req)
-	if p, err := io.ReadAll(resp.Body); err != nil {
-		t.Fail()
-	} else {
-		assert.Equal(t, http.StatusOK, resp.Code)
-		assert.Equal(t, "image/svg+xml", resp.Header().Get("Content-Type"))
-		// Check whether there is a SVG in the response, format checks are in other checks
-		assert.Contains(t, string(p), "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"133\" height=\"20\">")
-		assert.NotContains(t, string(p), "#4c1", "default color should not be set")
-		assert.Contains(t, string(p), "#572", "given color should be set")
+	path := t.TempDir() + "/custom.ttf"
+	if err = os.WriteFile(path, font, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	configStore["font.custom.path"] = path
+	defer delete(configStore, "font.custom.path")
+
+	for query, expect := range map[string]string{
+		"font=custom": `font-family="DejaVu Sans,DejaVu Sans,Verdana,Geneva,sans-serif"`,
+		"font=comic":  ">invalid font</text>",
+		"size=99":     ">invalid size</text>",
+		"size=big":    ">invalid size</text>",
+	} {
+		resp, p := doRequest(t, "GET", "/static/API/Documentation?"+query, nil, nil)
+		assert.Equal(t, http.StatusOK, resp.Code, query)
+		assert.Contains(t, p, expect, query)
 	}
 }
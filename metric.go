@@ -15,3 +15,14 @@ func metricFormat(in int64) string {
 	}
 	return fmt.Sprintf("%d", in)
 }
+
+func byteFormat(in int64) string {
+	units := []string{"kB", "MB", "GB", "TB", "PB", "EB"}
+	for i := len(units) - 1; i >= 0; i-- {
+		p := math.Pow(1000, float64(i+1)) //nolint:gomnd // Makes no sense to extract
+		if float64(in) >= p {
+			return fmt.Sprintf("%.1f %s", float64(in)/p, units[i])
+		}
+	}
+	return fmt.Sprintf("%d B", in)
+}
@@ -18,3 +18,18 @@ func TestMetricFormat(t *testing.T) {
 		}
 	}
 }
+
+func TestByteFormat(t *testing.T) {
+	cases := map[int64]string{
+		512:        "512 B",
+		1000:       "1.0 kB",
+		1234:       "1.2 kB",
+		28500000:   "28.5 MB",
+		1000000000: "1.0 GB",
+	}
+	for v, r := range cases {
+		if cr := byteFormat(v); cr != r {
+			t.Errorf("Byte format of number %d did not match '%s': '%s'", v, r, cr)
+		}
+	}
+}
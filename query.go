@@ -0,0 +1,499 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	xpathName          = regexp.MustCompile(`^(?:\*|[\w.-]+(?::[\w.-]+)?)$`)
+	xpathAttrPredicate = regexp.MustCompile(`^@([\w.-]+(?::[\w.-]+)?)\s*=\s*(?:'([^']*)'|"([^"]*)")$`)
+)
+
+type (
+	jsonPathSegment struct {
+		Recursive bool
+		Wildcard  bool
+		Key       string
+		Index     *int
+	}
+
+	xmlNode struct {
+		Name     string
+		Attrs    []xml.Attr
+		Children []*xmlNode
+		// Text contains the character data directly inside the node
+		Text string
+	}
+)
+
+// queryJSONPath evaluates a subset of JSONPath against the decoded
+// document: child access by name ($.a.b, $['a']), array indices
+// including negative ones ($.a[0], $.a[-1]), wildcards ($.a[*], $.*)
+// and recursive descent ($..name)
+func queryJSONPath(doc any, expr string) ([]any, error) {
+	segments, err := parseJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []any{doc}
+	for _, seg := range segments {
+		var next []any
+
+		for _, n := range nodes {
+			candidates := []any{n}
+			if seg.Recursive {
+				candidates = jsonDescendants(n, candidates)
+			}
+
+			for _, c := range candidates {
+				next = append(next, seg.apply(c)...)
+			}
+		}
+
+		nodes = next
+	}
+
+	return nodes, nil
+}
+
+func parseJSONPath(expr string) ([]jsonPathSegment, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, errors.New("JSONPath must start with $")
+	}
+
+	var (
+		segments []jsonPathSegment
+		rest     = expr[1:]
+	)
+
+	for rest != "" {
+		var seg jsonPathSegment
+
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			seg.Recursive = true
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				break
+			}
+			fallthrough
+
+		case strings.HasPrefix(rest, "."):
+			rest = strings.TrimPrefix(rest, ".")
+
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+
+			name := rest[:end]
+			if name == "" {
+				return nil, errors.Errorf("empty member name in JSONPath %q", expr)
+			}
+
+			seg.Wildcard = name == "*"
+			seg.Key = name
+			segments = append(segments, seg)
+			rest = rest[end:]
+			continue
+
+		case !strings.HasPrefix(rest, "["):
+			return nil, errors.Errorf("unexpected %q in JSONPath %q", rest, expr)
+		}
+
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return nil, errors.Errorf("unterminated bracket in JSONPath %q", expr)
+		}
+
+		sel := strings.TrimSpace(rest[1:end])
+		rest = rest[end+1:]
+
+		switch {
+		case sel == "*":
+			seg.Wildcard = true
+
+		case len(sel) >= 2 && (sel[0] == '\'' || sel[0] == '"') && sel[len(sel)-1] == sel[0]:
+			seg.Key = sel[1 : len(sel)-1]
+
+		default:
+			idx, err := strconv.Atoi(sel)
+			if err != nil {
+				return nil, errors.Errorf("unsupported selector %q in JSONPath %q", sel, expr)
+			}
+			seg.Index = &idx
+		}
+
+		segments = append(segments, seg)
+	}
+
+	return segments, nil
+}
+
+func (s jsonPathSegment) apply(n any) []any {
+	switch v := n.(type) {
+	case map[string]any:
+		if s.Wildcard {
+			out := make([]any, 0, len(v))
+			for _, k := range sortedKeys(v) {
+				out = append(out, v[k])
+			}
+			return out
+		}
+
+		if c, ok := v[s.Key]; ok && s.Index == nil {
+			return []any{c}
+		}
+
+	case []any:
+		if s.Wildcard {
+			return v
+		}
+
+		if s.Index != nil {
+			idx := *s.Index
+			if idx < 0 {
+				idx += len(v)
+			}
+			if idx >= 0 && idx < len(v) {
+				return []any{v[idx]}
+			}
+		}
+	}
+
+	return nil
+}
+
+// jsonDescendants appends all nodes below n to out in document order
+func jsonDescendants(n any, out []any) []any {
+	switch v := n.(type) {
+	case map[string]any:
+		for _, k := range sortedKeys(v) {
+			out = append(out, v[k])
+			out = jsonDescendants(v[k], out)
+		}
+
+	case []any:
+		for _, c := range v {
+			out = append(out, c)
+			out = jsonDescendants(c, out)
+		}
+	}
+
+	return out
+}
+
+// normalizeYAML converts the maps produced by the YAML decoder into
+// the map types produced by the JSON decoder to query them the same way
+func normalizeYAML(n any) any {
+	switch v := n.(type) {
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for k, c := range v {
+			out[formatQueryValue(k)] = normalizeYAML(c)
+		}
+		return out
+
+	case []any:
+		for i := range v {
+			v[i] = normalizeYAML(v[i])
+		}
+		return v
+
+	default:
+		return v
+	}
+}
+
+// formatQueryValue converts a single value returned by one of the
+// queries into its badge representation
+func formatQueryValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(t)
+	case bool:
+		return strconv.FormatBool(t)
+	case map[string]any:
+		keys := sortedKeys(t)
+		return "{" + strings.Join(keys, ", ") + "}"
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, c := range t {
+			parts = append(parts, formatQueryValue(c))
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// parseXMLTree reads the document into a tree of nodes below a
+// virtual document node
+func parseXMLTree(r io.Reader) (*xmlNode, error) {
+	var (
+		doc   = &xmlNode{}
+		stack = []*xmlNode{doc}
+		dec   = xml.NewDecoder(r)
+	)
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing XML")
+		}
+
+		cur := stack[len(stack)-1]
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{Name: t.Name.Local, Attrs: t.Attr}
+			cur.Children = append(cur.Children, n)
+			stack = append(stack, n)
+
+		case xml.EndElement:
+			cur.Text = strings.TrimSpace(cur.Text)
+			stack = stack[:len(stack)-1]
+
+		case xml.CharData:
+			cur.Text += string(t)
+		}
+	}
+
+	if len(doc.Children) == 0 {
+		return nil, errors.New("XML document has no root element")
+	}
+
+	return doc, nil
+}
+
+// textContent returns the text of the node and all its descendants
+func (n *xmlNode) textContent() string {
+	parts := []string{n.Text}
+	for _, c := range n.Children {
+		parts = append(parts, c.textContent())
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+func (n *xmlNode) descendants(out []*xmlNode) []*xmlNode {
+	for _, c := range n.Children {
+		out = append(out, c)
+		out = c.descendants(out)
+	}
+	return out
+}
+
+// queryXPath evaluates a subset of XPath against the document node:
+// child (/a/b) and descendant (//b) steps by name or wildcard, 1-based
+// position predicates (/a/b[2]), attribute equality predicates
+// (/a/b[@id='x']) and attribute (/a/@id) or text() selection as the
+// last step. Elements are returned with their text content.
+func queryXPath(doc *xmlNode, expr string) ([]string, error) {
+	if !strings.HasPrefix(expr, "/") {
+		return nil, errors.New("XPath must be absolute")
+	}
+
+	nodes := []*xmlNode{doc}
+	rest := expr
+
+	for rest != "" {
+		descendant := strings.HasPrefix(rest, "//")
+		rest = strings.TrimLeft(rest, "/")
+
+		step := rest
+		if end := xpathStepEnd(rest); end >= 0 {
+			step, rest = rest[:end], rest[end:]
+		} else {
+			rest = ""
+		}
+
+		if step == "" {
+			return nil, errors.Errorf("empty step in XPath %q", expr)
+		}
+
+		if step == "text()" || strings.HasPrefix(step, "@") {
+			if rest != "" {
+				return nil, errors.Errorf("%s must be the last step in XPath %q", step, expr)
+			}
+			return xpathSelectValues(nodes, step, descendant), nil
+		}
+
+		next, err := xpathSelectElements(nodes, step, descendant)
+		if err != nil {
+			return nil, errors.Wrapf(err, "evaluating XPath %q", expr)
+		}
+		nodes = next
+	}
+
+	out := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		out = append(out, n.textContent())
+	}
+
+	return out, nil
+}
+
+// xpathStepEnd finds the slash ending the current step while ignoring
+// slashes inside predicates
+func xpathStepEnd(expr string) int {
+	depth := 0
+	for i, c := range expr {
+		switch {
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '/' && depth == 0:
+			return i
+		}
+	}
+	return -1
+}
+
+func xpathSelectElements(nodes []*xmlNode, step string, descendant bool) ([]*xmlNode, error) {
+	name, predicate, hasPredicate := strings.Cut(step, "[")
+	if hasPredicate && !strings.HasSuffix(predicate, "]") {
+		return nil, errors.Errorf("unterminated predicate in step %q", step)
+	}
+	predicate = strings.TrimSuffix(predicate, "]")
+
+	if !xpathName.MatchString(name) {
+		// Axes, functions and other expressions are not supported
+		return nil, errors.Errorf("unsupported step %q", step)
+	}
+	name = xpathLocalName(name)
+
+	var out []*xmlNode
+	for _, n := range nodes {
+		// Predicates apply to the children of every parent separately
+		// so //b[1] selects each b being the first b of its parent
+		parents := []*xmlNode{n}
+		if descendant {
+			parents = n.descendants(parents)
+		}
+
+		selected := map[*xmlNode]bool{}
+		for _, p := range parents {
+			var matched []*xmlNode
+			for _, c := range p.Children {
+				if name == "*" || c.Name == name {
+					matched = append(matched, c)
+				}
+			}
+
+			matched, err := xpathFilter(matched, predicate)
+			if err != nil {
+				return nil, err
+			}
+
+			if !descendant {
+				out = append(out, matched...)
+				continue
+			}
+
+			for _, c := range matched {
+				selected[c] = true
+			}
+		}
+
+		if !descendant {
+			continue
+		}
+
+		// Results are returned in document order
+		for _, c := range n.descendants(nil) {
+			if selected[c] {
+				out = append(out, c)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func xpathFilter(nodes []*xmlNode, predicate string) ([]*xmlNode, error) {
+	predicate = strings.TrimSpace(predicate)
+	if predicate == "" {
+		return nodes, nil
+	}
+
+	if pos, err := strconv.Atoi(predicate); err == nil {
+		if pos < 1 || pos > len(nodes) {
+			return nil, nil
+		}
+		return nodes[pos-1 : pos], nil
+	}
+
+	m := xpathAttrPredicate.FindStringSubmatch(predicate)
+	if m == nil {
+		return nil, errors.Errorf("unsupported predicate %q", predicate)
+	}
+
+	attr, value := xpathLocalName(m[1]), m[2]+m[3]
+
+	var out []*xmlNode
+	for _, n := range nodes {
+		for _, a := range n.Attrs {
+			if a.Name.Local == attr && a.Value == value {
+				out = append(out, n)
+				break
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func xpathSelectValues(nodes []*xmlNode, step string, descendant bool) []string {
+	var out []string
+
+	for _, n := range nodes {
+		candidates := []*xmlNode{n}
+		if descendant {
+			candidates = n.descendants(candidates)
+		}
+
+		for _, c := range candidates {
+			if step == "text()" {
+				if c.Text != "" {
+					out = append(out, c.Text)
+				}
+				continue
+			}
+
+			for _, a := range c.Attrs {
+				if step == "@*" || a.Name.Local == xpathLocalName(step[1:]) {
+					out = append(out, a.Value)
+				}
+			}
+		}
+	}
+
+	return out
+}
+
+// xpathLocalName strips the namespace prefix from the name as
+// namespaces are not resolved and only local names are compared
+func xpathLocalName(name string) string {
+	if idx := strings.Index(name, ":"); idx >= 0 {
+		return name[idx+1:]
+	}
+	return name
+}
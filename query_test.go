@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestQueryJSONPath(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{
+		"version": "1.2.3",
+		"stats": {"up": true, "load": 0.5},
+		"nodes": [{"name": "a", "state": "ok"}, {"name": "b", "state": "down"}]
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		// Root and member access
+		"$":                  "{nodes, stats, version}",
+		"$.version":          "1.2.3",
+		"$['stats'].up":      "true",
+		`$["stats"]["load"]`: "0.5",
+		"$.stats.load":       "0.5",
+		"$.nodes[0]['name']": "a",
+		"$.missing":          "",
+		"$.version.missing":  "",
+		// Array indices
+		"$.nodes[1].state":  "down",
+		"$.nodes[-1].name":  "b",
+		"$.nodes[-2].name":  "a",
+		"$.nodes[2]":        "",
+		"$.nodes[-3]":       "",
+		"$.version[0]":      "",
+		"$.stats[0]":        "",
+		"$[ 0 ]":            "",
+		"$.nodes[ 1 ].name": "b",
+		// Wildcards
+		"$.nodes[*].name": "a, b",
+		"$.stats.*":       "0.5, true",
+		"$.stats[*]":      "0.5, true",
+		"$.version.*":     "",
+		"$.version[*]":    "",
+		// Recursive descent
+		"$..state":    "ok, down",
+		"$..[1].name": "b",
+		"$..up":       "true",
+		"$..missing":  "",
+	}
+
+	for expr, expect := range cases {
+		values, err := queryJSONPath(doc, expr)
+		if err != nil {
+			t.Errorf("Query %q failed: %s", expr, err)
+			continue
+		}
+
+		parts := []string{}
+		for _, v := range values {
+			parts = append(parts, formatQueryValue(v))
+		}
+
+		if r := strings.Join(parts, ", "); r != expect {
+			t.Errorf("Query %q yielded %q, expected %q", expr, r, expect)
+		}
+	}
+
+	// Filters, slices, unions and script expressions are not supported
+	for _, expr := range []string{
+		"version", "$version", "$.", "$.a..", "$.nodes[", "$.nodes[x]",
+		"$.nodes[0:1]", "$.nodes[0,1]", "$.nodes[?(@.state=='ok')]", "$.nodes[(@.length-1)]",
+	} {
+		if _, err := queryJSONPath(doc, expr); err == nil {
+			t.Errorf("Query %q did not fail", expr)
+		}
+	}
+}
+
+func TestQueryJSONPathYAML(t *testing.T) {
+	var doc any
+	if err := yaml.Unmarshal([]byte("release:\n  tag: v2\n  assets: [a, b]\n"), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := queryJSONPath(normalizeYAML(doc), "$.release.assets[1]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 1 || formatQueryValue(values[0]) != "b" {
+		t.Errorf("Unexpected query result %v", values)
+	}
+}
+
+func TestQueryXPath(t *testing.T) {
+	doc, err := parseXMLTree(strings.NewReader(`<?xml version="1.0"?>
+<status>
+	<build number="42" result="success">Build <b>OK</b></build>
+	<node id="a">up</node>
+	<node id="b">down</node>
+</status>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		// Child and descendant steps
+		"/status/build":   "Build OK",
+		"/status/missing": "",
+		"//b":             "OK",
+		"/status//b":      "OK",
+		"/*/node":         "up, down",
+		"//*[1]":          "Build OK up down, Build OK, OK",
+		"//node[2]":       "down",
+		// Position predicates
+		"/status/node[2]":   "down",
+		"/status/node[ 1 ]": "up",
+		"/status/node[3]":   "",
+		"/status/node[0]":   "",
+		// Attribute predicates
+		"/status/node[@id='a']":   "up",
+		`/status/node[@id="b"]`:   "down",
+		"/status/node[@id = 'a']": "up",
+		"/status/node[@id='c']":   "",
+		// Attribute and text selection
+		"/status/build/@number": "42",
+		"//build/@result":       "success",
+		"//node/@id":            "a, b",
+		"/status/build/@*":      "42, success",
+		"/status/*[1]/text()":   "Build",
+		"/status/node/text()":   "up, down",
+	}
+
+	for expr, expect := range cases {
+		values, err := queryXPath(doc, expr)
+		if err != nil {
+			t.Errorf("Query %q failed: %s", expr, err)
+			continue
+		}
+
+		if r := strings.Join(values, ", "); r != expect {
+			t.Errorf("Query %q yielded %q, expected %q", expr, r, expect)
+		}
+	}
+
+	// Relative paths, axes, functions and boolean predicates are not
+	// supported
+	for _, expr := range []string{
+		"status/build", "/status/@id/text()", "/status//", "/status/node[last()]",
+		"/status/node[@id='a' and @id='b']", "/status/node[@id=a]", "/status/child::node",
+		"count(//node)", "/status/node[1",
+	} {
+		if _, err := queryXPath(doc, expr); err == nil {
+			t.Errorf("Query %q did not fail", expr)
+		}
+	}
+}
+
+func TestQueryXPathNamespaces(t *testing.T) {
+	doc, err := parseXMLTree(strings.NewReader(`<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+	<entry><title>v1.2.0</title><media:thumbnail media:url="a.png"/></entry>
+	<entry><title>v1.1.0</title><media:thumbnail media:url="b.png"/></entry>
+</feed>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Prefixes are not resolved, names are matched by their local part
+	cases := map[string]string{
+		"/feed/entry[1]/title":                       "v1.2.0",
+		"/atom:feed/atom:entry[2]/atom:title":        "v1.1.0",
+		"//media:thumbnail/@media:url":               "a.png, b.png",
+		"//thumbnail/@url":                           "a.png, b.png",
+		"//media:thumbnail[@media:url='b.png']/@url": "b.png",
+	}
+
+	for expr, expect := range cases {
+		values, err := queryXPath(doc, expr)
+		if err != nil {
+			t.Errorf("Query %q failed: %s", expr, err)
+			continue
+		}
+
+		if r := strings.Join(values, ", "); r != expect {
+			t.Errorf("Query %q yielded %q, expected %q", expr, r, expect)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import "sort"
+
+// registryBadgeColor determines the color shared by the badges of
+// all package registry handlers for the given command
+func registryBadgeColor(command, text string) string {
+	switch command {
+	case "version":
+		return versionColor(text)
+	case "downloads":
+		return colorNameBrightGreen
+	default:
+		return colorNameBlue
+	}
+}
+
+func registryPeriodSuffix(period string) string {
+	return map[string]string{
+		"daily":   "/day",
+		"weekly":  "/week",
+		"monthly": "/month",
+		"yearly":  "/year",
+	}[period]
+}
+
+// sortedKeys returns the keys of the map in lexical order to create
+// stable output from maps
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// #configStore remote.allowed_hosts - []string - Hosts the dynamic, endpoint and http services may fetch from, "*.example.com" also allows all subdomains (services are disabled when unset)
+const configKeyRemoteAllowedHosts = "remote.allowed_hosts"
+
+const remoteMaxRedirects = 10
+
+// remoteHTTPClient must be used to fetch user supplied URLs as it
+// checks redirects against the allowlist: otherwise an allowed host
+// could redirect the request to internal addresses
+var remoteHTTPClient = &http.Client{
+	CheckRedirect: checkRemoteRedirect,
+}
+
+// remoteFetchEnabled reports whether any hosts were allowed for the
+// services fetching user supplied URLs
+func remoteFetchEnabled() bool {
+	return len(configStore.StrSlice(configKeyRemoteAllowedHosts)) > 0
+}
+
+// parseRemoteURL parses the user supplied URL and ensures it points to
+// a HTTP(S) host on the allowlist to prevent using the services
+// fetching user supplied URLs as an open proxy
+func parseRemoteURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing URL")
+	}
+
+	if err = checkRemoteURL(u); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func checkRemoteURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+
+	if !remoteHostAllowed(u.Hostname()) {
+		return errors.Errorf("host %q is not allowed", u.Hostname())
+	}
+
+	return nil
+}
+
+// checkRemoteRedirect ensures every redirect target is allowed
+func checkRemoteRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= remoteMaxRedirects {
+		return errors.Errorf("stopped after %d redirects", remoteMaxRedirects)
+	}
+
+	return errors.Wrap(checkRemoteURL(req.URL), "following redirect")
+}
+
+func remoteHostAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return false
+	}
+
+	for _, allowed := range configStore.StrSlice(configKeyRemoteAllowedHosts) {
+		allowed = strings.ToLower(allowed)
+
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+			if host == suffix || strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+
+		if host == allowed {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteHostAllowed(t *testing.T) {
+	configStore[configKeyRemoteAllowedHosts] = []interface{}{"example.com", "*.example.org"}
+	defer delete(configStore, configKeyRemoteAllowedHosts)
+
+	cases := map[string]bool{
+		"example.com":         true,
+		"EXAMPLE.com.":        true,
+		"api.example.com":     false,
+		"example.org":         true,
+		"api.example.org":     true,
+		"a.b.example.org":     true,
+		"evilexample.org":     false,
+		"example.org.evil.io": false,
+		"169.254.169.254":     false,
+		"":                    false,
+	}
+
+	for host, expect := range cases {
+		if res := remoteHostAllowed(host); res != expect {
+			t.Errorf("remoteHostAllowed(%q) = %v, expected %v", host, res, expect)
+		}
+	}
+}
+
+func TestRemoteRedirect(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"secret":"leaked"}`))
+	}))
+	defer internal.Close()
+
+	allowed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/internal":
+			// Same server addressed through a host not on the allowlist
+			http.Redirect(w, r, strings.Replace(internal.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+		case "/moved":
+			http.Redirect(w, r, "/data", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte(`{"secret":"public"}`))
+		}
+	}))
+	defer allowed.Close()
+
+	configStore[configKeyRemoteAllowedHosts] = []interface{}{"127.0.0.1"}
+	defer delete(configStore, configKeyRemoteAllowedHosts)
+
+	for path, expect := range map[string]string{
+		"/moved":    "public",
+		"/internal": "",
+	} {
+		u, err := parseRemoteURL(allowed.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out := struct {
+			Secret string `json:"secret"`
+		}{}
+
+		req, _ := http.NewRequest("GET", u.String(), nil) //nolint:noctx // fine for an internal test
+		err = fetchJSONWith(remoteHTTPClient, req, &out)
+
+		assert.Equal(t, expect, out.Secret, path)
+		if expect == "" {
+			assert.ErrorContains(t, err, `host "localhost" is not allowed`, path)
+		}
+	}
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -13,7 +15,20 @@ import (
 	"golang.org/x/net/context"
 )
 
-const aurCacheDuration = 10 * time.Minute
+const (
+	aurCacheDuration = 10 * time.Minute
+	aurBatchWindow   = 50 * time.Millisecond
+	aurBatchTimeout  = 10 * time.Second
+	// aurBatchMaxArgs keeps the request URI of a batch within the
+	// limits of the AUR web server
+	aurBatchMaxArgs = 100
+)
+
+var (
+	aurInfoBatcher = &aurBatcher{Window: aurBatchWindow}
+
+	errAURPackageNotFound = errors.New("No package was found")
+)
 
 func init() {
 	registerServiceHandler("aur", aurServiceHandler{})
@@ -22,29 +37,32 @@ func init() {
 type aurServiceHandler struct{}
 
 type aurInfoResult struct {
-	Version     int    `json:"version"`
-	Type        string `json:"type"`
-	Resultcount int    `json:"resultcount"`
-	Results     []struct {
-		ID             int      `json:"ID"`
-		Name           string   `json:"Name"`
-		PackageBaseID  int      `json:"PackageBaseID"`
-		PackageBase    string   `json:"PackageBase"`
-		Version        string   `json:"Version"`
-		Description    string   `json:"Description"`
-		URL            string   `json:"URL"`
-		NumVotes       int      `json:"NumVotes"`
-		Popularity     float64  `json:"Popularity"`
-		OutOfDate      int      `json:"OutOfDate"`
-		Maintainer     string   `json:"Maintainer"`
-		FirstSubmitted int      `json:"FirstSubmitted"`
-		LastModified   int      `json:"LastModified"`
-		URLPath        string   `json:"URLPath"`
-		Depends        []string `json:"Depends"`
-		License        []string `json:"License"`
-		Keywords       []string `json:"Keywords"`
-		MakeDepends    []string `json:"MakeDepends,omitempty"`
-	} `json:"results"`
+	Version     int              `json:"version"`
+	Type        string           `json:"type"`
+	Resultcount int              `json:"resultcount"`
+	Results     []aurPackageInfo `json:"results"`
+	Error       string           `json:"error"`
+}
+
+type aurPackageInfo struct {
+	ID             int      `json:"ID"`
+	Name           string   `json:"Name"`
+	PackageBaseID  int      `json:"PackageBaseID"`
+	PackageBase    string   `json:"PackageBase"`
+	Version        string   `json:"Version"`
+	Description    string   `json:"Description"`
+	URL            string   `json:"URL"`
+	NumVotes       int      `json:"NumVotes"`
+	Popularity     float64  `json:"Popularity"`
+	OutOfDate      int      `json:"OutOfDate"`
+	Maintainer     string   `json:"Maintainer"`
+	FirstSubmitted int      `json:"FirstSubmitted"`
+	LastModified   int      `json:"LastModified"`
+	URLPath        string   `json:"URLPath"`
+	Depends        []string `json:"Depends"`
+	License        []string `json:"License"`
+	Keywords       []string `json:"Keywords"`
+	MakeDepends    []string `json:"MakeDepends,omitempty"`
 }
 
 func (aurServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
@@ -52,7 +70,7 @@ func (aurServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
 		{
 			ServiceName: "AUR package version",
 			DemoPath:    "/aur/version/yay",
-			Arguments:   []string{"version", "<package name>"},
+			Arguments:   []string{"version", "<package name or base>"},
 		},
 		{
 			ServiceName: "AUR package votes",
@@ -69,6 +87,31 @@ func (aurServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
 			DemoPath:    "/aur/updated/yay",
 			Arguments:   []string{"updated", "<package name>"},
 		},
+		{
+			ServiceName: "AUR package popularity",
+			DemoPath:    "/aur/popularity/yay",
+			Arguments:   []string{"popularity", "<package name>"},
+		},
+		{
+			ServiceName: "AUR package maintainer",
+			DemoPath:    "/aur/maintainer/yay",
+			Arguments:   []string{"maintainer", "<package name>"},
+		},
+		{
+			ServiceName: "AUR package out-of-date flag",
+			DemoPath:    "/aur/out-of-date/yay",
+			Arguments:   []string{"out-of-date", "<package name>"},
+		},
+		{
+			ServiceName: "AUR package first submission",
+			DemoPath:    "/aur/first-submitted/yay",
+			Arguments:   []string{"first-submitted", "<package name>"},
+		},
+		{
+			ServiceName: "AUR package dependencies",
+			DemoPath:    "/aur/depends/yay",
+			Arguments:   []string{"depends", "<package name>"},
+		},
 	}
 }
 
@@ -79,108 +122,239 @@ func (a aurServiceHandler) Handle(ctx context.Context, params []string) (title,
 		return title, text, color, errors.New("No service-command / parameters were given")
 	}
 
+	var handle func(*aurPackageInfo) (title, text, color string)
 	switch params[0] {
+	case "depends":
+		handle = a.handleAURDepends
+	case "first-submitted":
+		handle = a.handleAURFirstSubmitted
 	case "license": //nolint:goconst
-		return a.handleAURLicense(ctx, params[1:])
+		handle = a.handleAURLicense
+	case "maintainer":
+		handle = a.handleAURMaintainer
+	case "out-of-date":
+		handle = a.handleAUROutOfDate
+	case "popularity":
+		handle = a.handleAURPopularity
 	case "updated":
-		return a.handleAURUpdated(ctx, params[1:])
+		handle = a.handleAURUpdated
 	case "version":
-		return a.handleAURVersion(ctx, params[1:])
+		handle = a.handleAURVersion
 	case "votes":
-		return a.handleAURVotes(ctx, params[1:])
+		handle = a.handleAURVotes
 	default:
 		return title, text, color, errors.New("An unknown service command was called")
 	}
+
+	info, err := a.fetchAURInfo(ctx, params[1])
+	if err != nil {
+		return title, text, color, err
+	}
+
+	title, text, color = handle(info)
+	return title, text, color, nil
 }
 
-func (a aurServiceHandler) handleAURLicense(ctx context.Context, params []string) (title, text, color string, err error) {
-	title = params[0]
-	text, err = cacheStore.Get("aur_license", title)
+func (aurServiceHandler) handleAURDepends(info *aurPackageInfo) (title, text, color string) {
+	return "dependencies", strconv.Itoa(len(info.Depends)), colorNameBlue
+}
 
-	if err != nil {
-		info, err := a.fetchAURInfo(ctx, params[0])
-		if err != nil {
-			return title, text, color, err
-		}
+func (aurServiceHandler) handleAURFirstSubmitted(info *aurPackageInfo) (title, text, color string) {
+	return "first submitted", time.Unix(int64(info.FirstSubmitted), 0).Format("2006-01-02"), colorNameBlue
+}
+
+func (aurServiceHandler) handleAURLicense(info *aurPackageInfo) (title, text, color string) {
+	return "license", strings.Join(info.License, ", "), colorNameBlue
+}
 
-		text = strings.Join(info.Results[0].License, ", ")
+func (aurServiceHandler) handleAURMaintainer(info *aurPackageInfo) (title, text, color string) {
+	if info.Maintainer == "" {
+		return "maintainer", "orphaned", colorNameRed
+	}
+
+	return "maintainer", info.Maintainer, colorNameBlue
+}
 
-		logErr(cacheStore.Set("aur_license", title, text, aurCacheDuration), "writing AUR license to cache")
+func (aurServiceHandler) handleAUROutOfDate(info *aurPackageInfo) (title, text, color string) {
+	if info.OutOfDate == 0 {
+		return "out of date", "no", colorNameBrightGreen
 	}
 
-	return "license", text, colorNameBlue, nil
+	return "out of date", "since " + time.Unix(int64(info.OutOfDate), 0).Format("2006-01-02"), colorNameRed
 }
 
-func (a aurServiceHandler) handleAURVersion(ctx context.Context, params []string) (title, text, color string, err error) {
-	title = params[0]
-	text, err = cacheStore.Get("aur_version", title)
+func (aurServiceHandler) handleAURPopularity(info *aurPackageInfo) (title, text, color string) {
+	return "popularity", fmt.Sprintf("%.2f", info.Popularity), colorNameBrightGreen
+}
 
-	if err != nil {
-		info, err := a.fetchAURInfo(ctx, params[0])
-		if err != nil {
-			return title, text, color, err
-		}
+func (aurServiceHandler) handleAURVersion(info *aurPackageInfo) (title, text, color string) {
+	return info.Name, info.Version, colorNameBlue
+}
 
-		text = info.Results[0].Version
+func (aurServiceHandler) handleAURUpdated(info *aurPackageInfo) (title, text, color string) {
+	update := time.Unix(int64(info.LastModified), 0)
+	text = update.Format("2006-01-02 15:04:05")
 
-		logErr(cacheStore.Set("aur_version", title, text, aurCacheDuration), "writing AUR version to cache")
+	color = colorNameBlue
+	if info.OutOfDate > 0 {
+		text += " (outdated)"
+		color = "red"
 	}
 
-	return title, text, colorNameBlue, nil
+	return "last updated", text, color
+}
+
+func (aurServiceHandler) handleAURVotes(info *aurPackageInfo) (title, text, color string) {
+	return info.Name, strconv.Itoa(info.NumVotes) + " votes", colorNameBrightGreen
 }
 
-func (a aurServiceHandler) handleAURUpdated(ctx context.Context, params []string) (title, text, color string, err error) {
-	title = params[0]
-	text, err = cacheStore.Get("aur_updated", title)
+// fetchAURInfo retrieves the package info from the AUR RPC and keeps
+// it in the cache for all sub-commands to share. If no package with
+// the given name exists the name is looked up as a package base.
+func (a aurServiceHandler) fetchAURInfo(ctx context.Context, pkg string) (*aurPackageInfo, error) {
+	info := &aurPackageInfo{}
+
+	if cached, err := cacheStore.Get("aur_info", pkg); err == nil {
+		return info, errors.Wrap(json.Unmarshal([]byte(cached), info), "Failed to parse cached AUR info")
+	}
+
+	info, err := aurInfoBatcher.Lookup(ctx, pkg)
+	if errors.Is(err, errAURPackageNotFound) {
+		info, err = a.fetchAURPackageBase(ctx, pkg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cached, err := json.Marshal(info)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode AUR info")
+	}
+	logErr(cacheStore.Set("aur_info", pkg, string(cached), aurCacheDuration), "writing AUR info to cache")
+
+	return info, nil
+}
 
+// fetchAURPackageBase resolves the info of a split package base using
+// one of the packages built from it: they share version, votes and
+// maintainer of the base
+func (aurServiceHandler) fetchAURPackageBase(ctx context.Context, base string) (*aurPackageInfo, error) {
+	out, err := aurRPC(ctx, url.Values{
+		"type": []string{"search"},
+		"by":   []string{"name"},
+		"arg":  []string{base},
+	})
 	if err != nil {
-		info, err := a.fetchAURInfo(ctx, params[0])
+		return nil, err
+	}
+
+	for _, r := range out.Results {
+		if r.PackageBase != base {
+			continue
+		}
+
+		info, err := aurInfoBatcher.Lookup(ctx, r.Name)
 		if err != nil {
-			return title, text, color, err
+			return nil, err
 		}
 
-		update := time.Unix(int64(info.Results[0].LastModified), 0)
-		text = update.Format("2006-01-02 15:04:05")
+		// Copy as the info is shared with other lookups of the package
+		baseInfo := *info
+		baseInfo.Name = base
+		return &baseInfo, nil
+	}
 
-		if info.Results[0].OutOfDate > 0 {
-			text += " (outdated)"
-		}
+	return nil, errAURPackageNotFound
+}
 
-		logErr(cacheStore.Set("aur_updated", title, text, aurCacheDuration), "writing AUR updated to cache")
+type (
+	// aurBatcher collects the package lookups arriving within a short
+	// window and resolves them using a single multiinfo request
+	aurBatcher struct {
+		Window time.Duration
+
+		lock    sync.Mutex
+		pending map[string][]chan aurLookupResult
 	}
 
-	color = colorNameBlue
-	if strings.Contains(text, "outdated") {
-		color = "red"
+	aurLookupResult struct {
+		Info *aurPackageInfo
+		Err  error
 	}
+)
 
-	return "last updated", text, color, nil
-}
+// Lookup queues the package for the next batch and waits for the
+// result of that batch
+func (b *aurBatcher) Lookup(ctx context.Context, pkg string) (*aurPackageInfo, error) {
+	res := make(chan aurLookupResult, 1)
 
-func (a aurServiceHandler) handleAURVotes(ctx context.Context, params []string) (title, text, color string, err error) {
-	title = params[0]
-	text, err = cacheStore.Get("aur_votes", title)
+	b.lock.Lock()
+	if b.pending == nil {
+		b.pending = map[string][]chan aurLookupResult{}
+		time.AfterFunc(b.Window, b.flush)
+	}
+	b.pending[pkg] = append(b.pending[pkg], res)
+	b.lock.Unlock()
+
+	select {
+	case r := <-res:
+		return r.Info, r.Err
+	case <-ctx.Done():
+		return nil, errors.Wrap(ctx.Err(), "Failed to wait for AUR info")
+	}
+}
 
-	if err != nil {
-		info, err := a.fetchAURInfo(ctx, params[0])
-		if err != nil {
-			return title, text, color, err
+func (b *aurBatcher) flush() {
+	b.lock.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.lock.Unlock()
+
+	names := sortedKeys(pending)
+	for len(names) > 0 {
+		chunk := names[:min(len(names), aurBatchMaxArgs)]
+		names = names[len(chunk):]
+
+		// The batch is not bound to any of the requests waiting for it
+		ctx, cancel := context.WithTimeout(context.Background(), aurBatchTimeout)
+		infos, err := fetchAURMultiInfo(ctx, chunk)
+		cancel()
+
+		for _, name := range chunk {
+			r := aurLookupResult{Info: infos[name], Err: err}
+			if err == nil && r.Info == nil {
+				r.Err = errAURPackageNotFound
+			}
+
+			for _, c := range pending[name] {
+				c <- r
+			}
 		}
+	}
+}
 
-		text = strconv.Itoa(info.Results[0].NumVotes) + " votes"
+// fetchAURMultiInfo requests the info of all given packages at once
+// and returns the infos by package name
+func fetchAURMultiInfo(ctx context.Context, pkgs []string) (map[string]*aurPackageInfo, error) {
+	out, err := aurRPC(ctx, url.Values{
+		"type":  []string{"info"},
+		"arg[]": pkgs,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		logErr(cacheStore.Set("aur_votes", title, text, aurCacheDuration), "writing AUR votes to cache")
+	infos := map[string]*aurPackageInfo{}
+	for i := range out.Results {
+		infos[out.Results[i].Name] = &out.Results[i]
 	}
 
-	return title, text, colorNameBrightGreen, nil
+	return infos, nil
 }
 
-func (aurServiceHandler) fetchAURInfo(ctx context.Context, pkg string) (*aurInfoResult, error) {
-	params := url.Values{
-		"v":    []string{"5"},
-		"type": []string{"info"},
-		"arg":  []string{pkg},
-	}
+// aurRPC executes a call against the v5 AUR RPC interface
+func aurRPC(ctx context.Context, params url.Values) (*aurInfoResult, error) {
+	params.Set("v", "5")
 	u := "https://aur.archlinux.org/rpc/?" + params.Encode()
 
 	req, _ := http.NewRequest("GET", u, nil)
@@ -194,13 +368,15 @@ func (aurServiceHandler) fetchAURInfo(ctx context.Context, pkg string) (*aurInfo
 		}
 	}()
 
+	// Errors are reported with a non-200 status on newer versions of
+	// the RPC therefore the body is parsed regardless of the status
 	out := &aurInfoResult{}
 	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
 		return nil, errors.Wrap(err, "Failed to parse AUR info")
 	}
 
-	if out.Resultcount == 0 {
-		return nil, errors.New("No package was found")
+	if out.Type == "error" {
+		return nil, errors.Errorf("AUR RPC returned an error: %s", out.Error)
 	}
 
 	return out, nil
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	bitbucketCacheDuration = 5 * time.Minute
+	bitbucketCloudAPI      = "https://api.bitbucket.org/2.0/"
+	bitbucketServerPageLen = 1000
+	// bitbucketServerMaxPages limits the pages fetched to count the
+	// pull requests in case the server keeps claiming more pages
+	bitbucketServerMaxPages = 20
+)
+
+const (
+	// #configStore bitbucket.username - string - Username for Bitbucket Cloud auth (required for private repos)
+	configKeyBitbucketUsername = "bitbucket.username"
+	// #configStore bitbucket.app_password - string - App password for Bitbucket Cloud auth (required for private repos)
+	configKeyBitbucketAppPassword = "bitbucket.app_password"
+	// #configStore bitbucket.<host>.url - string - Base URL of the Bitbucket Server instance addressed as <host> (host is rejected if unset)
+	configKeyBitbucketServerURL = "bitbucket.%s.url"
+	// #configStore bitbucket.<host>.token - string - HTTP access token for the Bitbucket Server instance addressed as <host>
+	configKeyBitbucketServerToken = "bitbucket.%s.token"
+	// #configStore bitbucket.<host>.username - string - Username for basic auth against <host> (used when no token is set)
+	configKeyBitbucketServerUsername = "bitbucket.%s.username"
+	// #configStore bitbucket.<host>.password - string - Password for basic auth against <host> (used when no token is set)
+	configKeyBitbucketServerPassword = "bitbucket.%s.password"
+)
+
+func init() {
+	registerServiceHandler("bitbucket", bitbucketServiceHandler{})
+}
+
+type bitbucketServiceHandler struct{}
+
+type bitbucketCloudPage struct {
+	Size   int             `json:"size"`
+	Values json.RawMessage `json:"values"`
+}
+
+type bitbucketServerPage struct {
+	IsLastPage    bool            `json:"isLastPage"`
+	NextPageStart int             `json:"nextPageStart"`
+	Size          int             `json:"size"`
+	Values        json.RawMessage `json:"values"`
+}
+
+func (bitbucketServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "Bitbucket pipeline status",
+			DemoPath:    "/bitbucket/pipeline/atlassian/aui",
+			Arguments:   []string{"pipeline", "<workspace>", "<repo>", "[branch]"},
+		},
+		{
+			ServiceName: "Bitbucket open pull requests",
+			DemoPath:    "/bitbucket/pull-requests/atlassian/aui",
+			Arguments:   []string{"pull-requests", "<workspace>", "<repo>"},
+		},
+		{
+			ServiceName: "Bitbucket latest tag",
+			DemoPath:    "/bitbucket/latest-tag/atlassian/aui",
+			Arguments:   []string{"latest-tag", "<workspace>", "<repo>"},
+		},
+		{
+			ServiceName: "Bitbucket open issues",
+			DemoPath:    "/bitbucket/issues/atlassian/python-bitbucket",
+			Arguments:   []string{"issues", "<workspace>", "<repo>"},
+		},
+		{
+			ServiceName: "Bitbucket Server build status",
+			DemoPath:    "/bitbucket/server/git.example.com/pipeline/PROJ/repo",
+			Arguments:   []string{"server", "<host>", "pipeline", "<project>", "<repo>", "[branch]"},
+		},
+		{
+			ServiceName: "Bitbucket Server open pull requests",
+			DemoPath:    "/bitbucket/server/git.example.com/pull-requests/PROJ/repo",
+			Arguments:   []string{"server", "<host>", "pull-requests", "<project>", "<repo>"},
+		},
+		{
+			ServiceName: "Bitbucket Server latest tag",
+			DemoPath:    "/bitbucket/server/git.example.com/latest-tag/PROJ/repo",
+			Arguments:   []string{"server", "<host>", "latest-tag", "<project>", "<repo>"},
+		},
+	}
+}
+
+func (bitbucketServiceHandler) IsEnabled() bool { return true }
+
+func (b bitbucketServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) > 0 && params[0] == "server" {
+		return b.handleServer(ctx, params[1:])
+	}
+
+	if len(params) < 3 { //nolint:gomnd
+		err = errors.New("you need to provide command, workspace and repo")
+		return title, text, color, err
+	}
+
+	switch params[0] {
+	case "pipeline":
+		title, text, color, err = b.handleCloudPipeline(ctx, params[1:])
+	case "pull-requests":
+		title, text, color, err = b.handleCloudPullRequests(ctx, params[1:])
+	case "latest-tag":
+		title, text, color, err = b.handleCloudLatestTag(ctx, params[1:])
+	case "issues":
+		title, text, color, err = b.handleCloudIssues(ctx, params[1:])
+	default:
+		err = errors.New("an unknown service command was called")
+	}
+
+	return title, text, color, err
+}
+
+func (b bitbucketServiceHandler) handleServer(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 4 { //nolint:gomnd
+		err = errors.New("you need to provide host, command, project and repo")
+		return title, text, color, err
+	}
+
+	host := params[0]
+	if configStore.Str(fmt.Sprintf(configKeyBitbucketServerURL, host)) == "" {
+		err = fmt.Errorf("bitbucket server %q is not configured", host)
+		return title, text, color, err
+	}
+
+	switch params[1] {
+	case "pipeline":
+		title, text, color, err = b.handleServerBuildStatus(ctx, host, params[2:])
+	case "pull-requests":
+		title, text, color, err = b.handleServerPullRequests(ctx, host, params[2:])
+	case "latest-tag":
+		title, text, color, err = b.handleServerLatestTag(ctx, host, params[2:])
+	case "issues":
+		err = errors.New("bitbucket server has no issue tracker")
+	default:
+		err = errors.New("an unknown service command was called")
+	}
+
+	return title, text, color, err
+}
+
+func (b bitbucketServiceHandler) handleCloudPipeline(ctx context.Context, params []string) (title, text, color string, err error) {
+	query := url.Values{
+		"sort":    []string{"-created_on"},
+		"pagelen": []string{"1"},
+	}
+	if len(params) > 2 { //nolint:gomnd
+		query.Set("target.ref_name", params[2])
+	}
+	path := strings.Join([]string{"repositories", params[0], params[1], "pipelines/"}, "/") + "?" + query.Encode()
+
+	text, err = cacheStore.Get("bitbucket_pipeline", path)
+
+	if err != nil {
+		var (
+			page      bitbucketCloudPage
+			pipelines []struct {
+				State struct {
+					Name   string `json:"name"`
+					Result *struct {
+						Name string `json:"name"`
+					} `json:"result"`
+				} `json:"state"`
+			}
+		)
+
+		if err = b.fetchCloudAPI(ctx, path, &page); err != nil {
+			return title, text, color, err
+		}
+
+		if err = json.Unmarshal(page.Values, &pipelines); err != nil {
+			return title, text, color, errors.Wrap(err, "decoding pipelines")
+		}
+
+		text = ciStatusUnknown
+		if len(pipelines) > 0 {
+			switch pipelines[0].State.Name {
+			case "PENDING":
+				text = "pending"
+			case "IN_PROGRESS":
+				text = "running"
+			case "COMPLETED":
+				if pipelines[0].State.Result == nil {
+					break
+				}
+				if s, ok := map[string]string{
+					"SUCCESSFUL": "passed",
+					"FAILED":     "failed",
+					"ERROR":      "errored",
+					"STOPPED":    "stopped",
+				}[pipelines[0].State.Result.Name]; ok {
+					text = s
+				}
+			}
+		}
+
+		logErr(cacheStore.Set("bitbucket_pipeline", path, text, bitbucketCacheDuration), "writing Bitbucket pipeline status to cache")
+	}
+
+	return "pipeline", text, ciStatusColor(text), nil
+}
+
+func (b bitbucketServiceHandler) handleCloudPullRequests(ctx context.Context, params []string) (title, text, color string, err error) {
+	path := strings.Join([]string{"repositories", params[0], params[1], "pullrequests"}, "/") + "?state=OPEN&pagelen=1"
+
+	text, err = cacheStore.Get("bitbucket_pull_requests", path)
+
+	if err != nil {
+		var page bitbucketCloudPage
+
+		if err = b.fetchCloudAPI(ctx, path, &page); err != nil {
+			return title, text, color, err
+		}
+
+		text = metricFormat(int64(page.Size))
+		logErr(cacheStore.Set("bitbucket_pull_requests", path, text, bitbucketCacheDuration), "writing Bitbucket pull requests to cache")
+	}
+
+	return "pull requests", text, colorNameBlue, nil
+}
+
+func (b bitbucketServiceHandler) handleCloudLatestTag(ctx context.Context, params []string) (title, text, color string, err error) {
+	path := strings.Join([]string{"repositories", params[0], params[1], "refs", "tags"}, "/") + "?sort=-target.date&pagelen=1"
+
+	text, err = cacheStore.Get("bitbucket_latest_tag", path)
+
+	if err != nil {
+		var (
+			page bitbucketCloudPage
+			tags []struct {
+				Name string `json:"name"`
+			}
+		)
+
+		if err = b.fetchCloudAPI(ctx, path, &page); err != nil {
+			return title, text, color, err
+		}
+
+		if err = json.Unmarshal(page.Values, &tags); err != nil {
+			return title, text, color, errors.Wrap(err, "decoding tags")
+		}
+
+		text = "None"
+		if len(tags) > 0 {
+			text = tags[0].Name
+		}
+		logErr(cacheStore.Set("bitbucket_latest_tag", path, text, bitbucketCacheDuration), "writing Bitbucket latest tag to cache")
+	}
+
+	return "tag", text, versionColor(text), nil
+}
+
+func (b bitbucketServiceHandler) handleCloudIssues(ctx context.Context, params []string) (title, text, color string, err error) {
+	query := url.Values{
+		"q":       []string{`state="new" OR state="open"`},
+		"pagelen": []string{"1"},
+	}
+	path := strings.Join([]string{"repositories", params[0], params[1], "issues"}, "/") + "?" + query.Encode()
+
+	text, err = cacheStore.Get("bitbucket_issues", path)
+
+	if err != nil {
+		var page bitbucketCloudPage
+
+		if err = b.fetchCloudAPI(ctx, path, &page); err != nil {
+			return title, text, color, err
+		}
+
+		text = metricFormat(int64(page.Size))
+		logErr(cacheStore.Set("bitbucket_issues", path, text, bitbucketCacheDuration), "writing Bitbucket issues to cache")
+	}
+
+	return "issues", text, colorNameBlue, nil
+}
+
+func (b bitbucketServiceHandler) handleServerBuildStatus(ctx context.Context, host string, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide project and repo")
+		return title, text, color, err
+	}
+
+	query := url.Values{"limit": []string{"1"}}
+	if len(params) > 2 { //nolint:gomnd
+		query.Set("until", params[2])
+	}
+	path := strings.Join([]string{"rest", "api", "1.0", "projects", params[0], "repos", params[1], "commits"}, "/") + "?" + query.Encode()
+	cacheKey := host + "/" + path
+
+	text, err = cacheStore.Get("bitbucket_server_build", cacheKey)
+
+	if err != nil {
+		var (
+			page    bitbucketServerPage
+			commits []struct {
+				ID string `json:"id"`
+			}
+			statuses []struct {
+				State string `json:"state"`
+			}
+		)
+
+		if err = b.fetchServerAPI(ctx, host, path, &page); err != nil {
+			return title, text, color, err
+		}
+
+		if err = json.Unmarshal(page.Values, &commits); err != nil {
+			return title, text, color, errors.Wrap(err, "decoding commits")
+		}
+
+		if len(commits) == 0 {
+			return title, text, color, errors.New("no commits found")
+		}
+
+		if err = b.fetchServerAPI(ctx, host, "rest/build-status/1.0/commits/"+commits[0].ID, &page); err != nil {
+			return title, text, color, err
+		}
+
+		if err = json.Unmarshal(page.Values, &statuses); err != nil {
+			return title, text, color, errors.Wrap(err, "decoding build statuses")
+		}
+
+		text = ciStatusUnknown
+		for _, s := range statuses {
+			switch s.State {
+			case "FAILED":
+				text = "failed"
+			case "INPROGRESS":
+				if text != "failed" {
+					text = "running"
+				}
+			case "SUCCESSFUL":
+				if text == ciStatusUnknown {
+					text = "passed"
+				}
+			}
+		}
+
+		logErr(cacheStore.Set("bitbucket_server_build", cacheKey, text, bitbucketCacheDuration), "writing Bitbucket Server build status to cache")
+	}
+
+	return "build", text, ciStatusColor(text), nil
+}
+
+func (b bitbucketServiceHandler) handleServerPullRequests(ctx context.Context, host string, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide project and repo")
+		return title, text, color, err
+	}
+
+	path := strings.Join([]string{"rest", "api", "1.0", "projects", params[0], "repos", params[1], "pull-requests"}, "/")
+	cacheKey := host + "/" + path
+
+	text, err = cacheStore.Get("bitbucket_server_pull_requests", cacheKey)
+
+	if err != nil {
+		var count, start int
+
+		for i := 0; i < bitbucketServerMaxPages; i++ {
+			query := url.Values{
+				"state": []string{"OPEN"},
+				"limit": []string{strconv.Itoa(bitbucketServerPageLen)},
+				"start": []string{strconv.Itoa(start)},
+			}
+
+			page := bitbucketServerPage{}
+			if err = b.fetchServerAPI(ctx, host, path+"?"+query.Encode(), &page); err != nil {
+				return title, text, color, err
+			}
+
+			count += page.Size
+			if page.IsLastPage || page.NextPageStart <= start {
+				// Servers not advancing the page would be asked for
+				// the same page over and over again
+				break
+			}
+			start = page.NextPageStart
+		}
+
+		text = metricFormat(int64(count))
+		logErr(cacheStore.Set("bitbucket_server_pull_requests", cacheKey, text, bitbucketCacheDuration), "writing Bitbucket Server pull requests to cache")
+	}
+
+	return "pull requests", text, colorNameBlue, nil
+}
+
+func (b bitbucketServiceHandler) handleServerLatestTag(ctx context.Context, host string, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide project and repo")
+		return title, text, color, err
+	}
+
+	path := strings.Join([]string{"rest", "api", "1.0", "projects", params[0], "repos", params[1], "tags"}, "/") + "?orderBy=MODIFICATION&limit=1"
+	cacheKey := host + "/" + path
+
+	text, err = cacheStore.Get("bitbucket_server_latest_tag", cacheKey)
+
+	if err != nil {
+		var (
+			page bitbucketServerPage
+			tags []struct {
+				DisplayID string `json:"displayId"`
+			}
+		)
+
+		if err = b.fetchServerAPI(ctx, host, path, &page); err != nil {
+			return title, text, color, err
+		}
+
+		if err = json.Unmarshal(page.Values, &tags); err != nil {
+			return title, text, color, errors.Wrap(err, "decoding tags")
+		}
+
+		text = "None"
+		if len(tags) > 0 {
+			text = tags[0].DisplayID
+		}
+		logErr(cacheStore.Set("bitbucket_server_latest_tag", cacheKey, text, bitbucketCacheDuration), "writing Bitbucket Server latest tag to cache")
+	}
+
+	return "tag", text, versionColor(text), nil
+}
+
+func (bitbucketServiceHandler) fetchCloudAPI(ctx context.Context, path string, out interface{}) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET", bitbucketCloudAPI+path, nil)
+
+	if configStore.Str(configKeyBitbucketAppPassword) != "" {
+		req.SetBasicAuth(configStore.Str(configKeyBitbucketUsername), configStore.Str(configKeyBitbucketAppPassword))
+	}
+
+	return fetchJSON(req, out)
+}
+
+func (bitbucketServiceHandler) fetchServerAPI(ctx context.Context, host, path string, out interface{}) error {
+	base := strings.TrimRight(configStore.Str(fmt.Sprintf(configKeyBitbucketServerURL, host)), "/")
+	req, _ := http.NewRequestWithContext(ctx, "GET", base+"/"+path, nil)
+
+	switch {
+	case configStore.Str(fmt.Sprintf(configKeyBitbucketServerToken, host)) != "":
+		req.Header.Set("Authorization", "Bearer "+configStore.Str(fmt.Sprintf(configKeyBitbucketServerToken, host)))
+	case configStore.Str(fmt.Sprintf(configKeyBitbucketServerPassword, host)) != "":
+		req.SetBasicAuth(
+			configStore.Str(fmt.Sprintf(configKeyBitbucketServerUsername, host)),
+			configStore.Str(fmt.Sprintf(configKeyBitbucketServerPassword, host)),
+		)
+	}
+
+	return fetchJSON(req, out)
+}
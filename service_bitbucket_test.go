@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestBitbucketServerPullRequests(t *testing.T) {
+	var requests int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		switch r.URL.Query().Get("start") {
+		case "0":
+			fmt.Fprint(w, `{"size":3,"isLastPage":false,"nextPageStart":3}`)
+		case "3":
+			// Server claims more pages without advancing
+			fmt.Fprint(w, `{"size":2,"isLastPage":false,"nextPageStart":3}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	configStore["bitbucket.test.url"] = srv.URL
+	defer delete(configStore, "bitbucket.test.url")
+
+	_, text, _, err := bitbucketServiceHandler{}.Handle(context.Background(), []string{"server", "test", "pull-requests", "PRJ", "repo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "5", text)
+	assert.Equal(t, 2, requests)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	buildkiteCacheDuration = 5 * time.Minute
+	buildkiteDefaultAPI    = "https://api.buildkite.com/v2"
+)
+
+const (
+	// #configStore buildkite.token - string - API access token with read_builds scope (required to enable the service)
+	configKeyBuildkiteToken = "buildkite.token"
+	// #configStore buildkite.url - string - Override the Buildkite API base URL (defaults to https://api.buildkite.com/v2)
+	configKeyBuildkiteURL = "buildkite.url"
+)
+
+func init() {
+	registerServiceHandler("buildkite", buildkiteServiceHandler{})
+}
+
+type buildkiteServiceHandler struct{}
+
+func (buildkiteServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{{
+		ServiceName: "Buildkite build status",
+		DemoPath:    "/buildkite/buildkite/agent/main",
+		Arguments:   []string{"<organization>", "<pipeline>", "[branch]"},
+	}}
+}
+
+func (buildkiteServiceHandler) IsEnabled() bool {
+	return configStore.Str(configKeyBuildkiteToken) != ""
+}
+
+func (buildkiteServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide organization and pipeline")
+		return title, text, color, err
+	}
+
+	query := url.Values{"per_page": []string{"1"}}
+	if len(params) > 2 { //nolint:gomnd
+		query.Set("branch", params[2])
+	}
+	path := strings.Join([]string{"organizations", url.PathEscape(params[0]), "pipelines", url.PathEscape(params[1]), "builds"}, "/") + "?" + query.Encode()
+
+	text, err = cacheStore.Get("buildkite", path)
+
+	if err != nil {
+		base := strings.TrimRight(configStore.StrDefault(configKeyBuildkiteURL, buildkiteDefaultAPI), "/")
+
+		req, _ := http.NewRequestWithContext(ctx, "GET", base+"/"+path, nil)
+		req.Header.Set("Authorization", "Bearer "+configStore.Str(configKeyBuildkiteToken))
+
+		r := []struct {
+			State string `json:"state"`
+		}{}
+
+		if err = fetchJSON(req, &r); err != nil {
+			return title, text, color, err
+		}
+
+		text = ciStatusUnknown
+		if len(r) > 0 {
+			if s, ok := map[string]string{
+				"passed":    "passed",
+				"failed":    "failed",
+				"failing":   "failed",
+				"canceled":  "canceled",
+				"canceling": "canceled",
+				"skipped":   "skipped",
+				"not_run":   "skipped",
+				"blocked":   "blocked",
+				"running":   "running",
+				"scheduled": "pending",
+			}[r[0].State]; ok {
+				text = s
+			}
+		}
+
+		logErr(cacheStore.Set("buildkite", path, text, buildkiteCacheDuration), "writing Buildkite status to cache")
+	}
+
+	return "build", text, ciStatusColor(text), nil
+}
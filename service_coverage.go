@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Luzifer/go_helpers/v2/str"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+const (
+	coverageCacheDuration   = 10 * time.Minute
+	coverageMaxReportSize   = 32 * 1024 * 1024 // 32MiB
+	codecovDefaultAPI       = "https://api.codecov.io/api/v2"
+	coverallsDefaultBaseURL = "https://coveralls.io"
+
+	// coverageUploadRetention is the time uploaded coverage is kept
+	// after the last upload for the repository / branch
+	coverageUploadRetention = 90 * 24 * time.Hour
+)
+
+const (
+	// #configStore codecov.token - string - Codecov API access token (optional, required for private repositories)
+	configKeyCodecovToken = "codecov.token"
+	// #configStore codecov.url - string - Override the Codecov API base URL (defaults to https://api.codecov.io/api/v2)
+	configKeyCodecovURL = "codecov.url"
+	// #configStore coveralls.url - string - Override the Coveralls base URL (defaults to https://coveralls.io)
+	configKeyCoverallsURL = "coveralls.url"
+	// #configStore coverage.<owner>.upload_token - string - Token required to upload coverage reports for repos of <owner> (uploads are disabled for owners without token, uploaded coverage is kept in memory only)
+	configKeyCoverageUploadToken = "coverage.%s.upload_token"
+)
+
+var coverageServices = []string{"github", "gitlab", "bitbucket"}
+
+func init() {
+	registerServiceHandler("coverage", coverageServiceHandler{})
+}
+
+type coverageServiceHandler struct{}
+
+func (coverageServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "Codecov coverage",
+			DemoPath:    "/coverage/codecov/github/codecov/codecov-cli",
+			Arguments:   []string{"codecov", "<github|gitlab|bitbucket>", "<owner>", "<repo>", "[branch]"},
+		},
+		{
+			ServiceName: "Coveralls coverage",
+			DemoPath:    "/coverage/coveralls/github/lemurheavy/coveralls-ruby",
+			Arguments:   []string{"coveralls", "<github|gitlab|bitbucket>", "<owner>", "<repo>", "[branch]"},
+		},
+		{
+			ServiceName: "Uploaded coverage report",
+			DemoPath:    "/coverage/local/Luzifer/badge-gen/master",
+			Arguments:   []string{"local", "<owner>", "<repo>", "[branch]"},
+		},
+	}
+}
+
+func (coverageServiceHandler) IsEnabled() bool { return true }
+
+func (c coverageServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 3 { //nolint:gomnd
+		err = errors.New("you need to provide source, owner and repo")
+		return title, text, color, err
+	}
+
+	var percent string
+
+	switch params[0] {
+	case "codecov", "coveralls":
+		if len(params) < 4 { //nolint:gomnd
+			err = errors.New("you need to provide service, owner and repo")
+			return title, text, color, err
+		}
+
+		if !str.StringInSlice(params[1], coverageServices) {
+			err = fmt.Errorf("unknown service %q", params[1])
+			return title, text, color, err
+		}
+
+		cacheKey := strings.Join(params, "/")
+		percent, err = cacheStore.Get("coverage", cacheKey)
+
+		if err != nil {
+			fetch := c.fetchCodecov
+			if params[0] == "coveralls" {
+				fetch = c.fetchCoveralls
+			}
+
+			var branch string
+			if len(params) > 4 { //nolint:gomnd
+				branch = params[4]
+			}
+
+			var p float64
+			if p, err = fetch(ctx, params[1], params[2], params[3], branch); err != nil {
+				return title, text, color, err
+			}
+			percent = strconv.FormatFloat(p, 'f', -1, 64)
+
+			logErr(cacheStore.Set("coverage", cacheKey, percent, coverageCacheDuration), "writing coverage to cache")
+		}
+
+	case "local":
+		if percent, err = cacheStore.Get("coverage_upload", strings.Join(params[1:], "/")); err != nil {
+			return "coverage", "unknown", colorNameLightGray, nil
+		}
+
+	default:
+		err = errors.New("an unknown service command was called")
+		return title, text, color, err
+	}
+
+	p, err := strconv.ParseFloat(percent, 64)
+	if err != nil {
+		return title, text, color, errors.Wrap(err, "parsing coverage")
+	}
+
+	return "coverage", coverageText(p), coverageColor(p), nil
+}
+
+func (coverageServiceHandler) fetchCodecov(ctx context.Context, service, owner, repo, branch string) (float64, error) {
+	base := strings.TrimRight(configStore.StrDefault(configKeyCodecovURL, codecovDefaultAPI), "/")
+	path := []string{base, url.PathEscape(service), url.PathEscape(owner), "repos", url.PathEscape(repo)}
+	if branch != "" {
+		path = append(path, "branches", url.PathEscape(branch))
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", strings.Join(path, "/")+"/", nil)
+	if token := configStore.Str(configKeyCodecovToken); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	// Repository and branch responses carry the totals in different
+	// places, both are decoded to avoid two distinct types
+	r := struct {
+		Totals *struct {
+			Coverage float64 `json:"coverage"`
+		} `json:"totals"`
+		HeadCommit *struct {
+			Totals *struct {
+				Coverage float64 `json:"coverage"`
+			} `json:"totals"`
+		} `json:"head_commit"`
+	}{}
+
+	if err := fetchJSON(req, &r); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case r.HeadCommit != nil && r.HeadCommit.Totals != nil:
+		return r.HeadCommit.Totals.Coverage, nil
+	case r.Totals != nil:
+		return r.Totals.Coverage, nil
+	default:
+		return 0, errors.New("no coverage reported")
+	}
+}
+
+func (coverageServiceHandler) fetchCoveralls(ctx context.Context, service, owner, repo, branch string) (float64, error) {
+	base := strings.TrimRight(configStore.StrDefault(configKeyCoverallsURL, coverallsDefaultBaseURL), "/")
+	u := strings.Join([]string{base, url.PathEscape(service), url.PathEscape(owner), url.PathEscape(repo)}, "/") + ".json"
+	if branch != "" {
+		u += "?" + url.Values{"branch": []string{branch}}.Encode()
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", u, nil)
+
+	r := struct {
+		CoveredPercent *float64 `json:"covered_percent"`
+	}{}
+
+	if err := fetchJSON(req, &r); err != nil {
+		return 0, err
+	}
+
+	if r.CoveredPercent == nil {
+		return 0, errors.New("no coverage reported")
+	}
+
+	return *r.CoveredPercent, nil
+}
+
+// handleCoverageUpload accepts a coverage report (Go cover profile,
+// Cobertura XML or LCOV) and stores the covered percentage for the
+// repository / branch given in the path to be used in the "local"
+// coverage badge
+func handleCoverageUpload(res http.ResponseWriter, r *http.Request) {
+	params := strings.Split(mux.Vars(r)["parameters"], "/")
+	for i := range params {
+		var err error
+		if params[i], err = url.QueryUnescape(params[i]); err != nil {
+			http.Error(res, "Invalid escaping in URL", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if len(params) < 2 || params[0] == "" || params[1] == "" { //nolint:gomnd
+		http.Error(res, "You must specify owner and repo.", http.StatusBadRequest)
+		return
+	}
+
+	// Tokens are scoped to the owner to not allow every uploader to
+	// overwrite the coverage of all repos
+	token := configStore.Str(fmt.Sprintf(configKeyCoverageUploadToken, params[0]))
+	if token == "" {
+		http.Error(res, "Coverage uploads are disabled for "+params[0], http.StatusNotFound)
+		return
+	}
+
+	if !hasBearerToken(r, token) {
+		http.Error(res, "Invalid upload token", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := io.ReadAll(http.MaxBytesReader(res, r.Body, coverageMaxReportSize))
+	if err != nil {
+		http.Error(res, "Unable to read coverage report", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = detectCoverageFormat(report)
+	}
+
+	percent, err := parseCoverageReport(format, report)
+	if err != nil {
+		http.Error(res, "Unable to parse coverage report: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = cacheStore.Set("coverage_upload", strings.Join(params, "/"), strconv.FormatFloat(percent, 'f', -1, 64), coverageUploadRetention); err != nil {
+		logrus.WithError(err).Error("storing uploaded coverage")
+		http.Error(res, "Unable to store coverage", http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/plain")
+	if _, err = fmt.Fprintln(res, coverageText(percent)); err != nil {
+		logrus.WithError(err).Error("writing upload response")
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	cratesCacheDuration = 30 * time.Minute
+	cratesDefaultAPI    = "https://crates.io"
+)
+
+// #configStore crates.api - string - Base URL of the crates.io API (defaults to https://crates.io)
+const configKeyCratesAPI = "crates.api"
+
+func init() {
+	registerServiceHandler("crates", cratesServiceHandler{})
+}
+
+type cratesServiceHandler struct{}
+
+type cratesCrate struct {
+	Crate struct {
+		Downloads        int64  `json:"downloads"`
+		RecentDownloads  int64  `json:"recent_downloads"`
+		MaxStableVersion string `json:"max_stable_version"`
+		MaxVersion       string `json:"max_version"`
+	} `json:"crate"`
+	Versions []cratesVersion `json:"versions"`
+}
+
+type cratesVersion struct {
+	Num         string `json:"num"`
+	License     string `json:"license"`
+	RustVersion string `json:"rust_version"`
+	Yanked      bool   `json:"yanked"`
+}
+
+func (cratesServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "crates.io version",
+			DemoPath:    "/crates/version/serde",
+			Arguments:   []string{"version", "<crate>", "[prerelease]"},
+		},
+		{
+			ServiceName: "crates.io downloads",
+			DemoPath:    "/crates/downloads/serde",
+			Arguments:   []string{"downloads", "<crate>", "[total|recent]"},
+		},
+		{
+			ServiceName: "crates.io license",
+			DemoPath:    "/crates/license/serde",
+			Arguments:   []string{"license", "<crate>"},
+		},
+		{
+			ServiceName: "crates.io minimum Rust version",
+			DemoPath:    "/crates/runtime/serde",
+			Arguments:   []string{"runtime", "<crate>"},
+		},
+	}
+}
+
+func (cratesServiceHandler) IsEnabled() bool { return true }
+
+func (c cratesServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide command and crate")
+		return title, text, color, err
+	}
+
+	var fetch func(context.Context, string, []string) (string, error)
+	switch params[0] {
+	case "version":
+		title, fetch = "crates.io", c.fetchVersion
+	case "downloads":
+		title, fetch = "downloads", c.fetchDownloads
+	case "license":
+		title, fetch = "license", c.fetchLicense
+	case "runtime":
+		title, fetch = "rust", c.fetchRuntime
+	default:
+		err = errors.New("an unknown service command was called")
+		return title, text, color, err
+	}
+
+	cacheKey := strings.Join(params, "/")
+	text, err = cacheStore.Get("crates", cacheKey)
+
+	if err != nil {
+		if text, err = fetch(ctx, params[1], params[2:]); err != nil {
+			return title, text, color, err
+		}
+
+		logErr(cacheStore.Set("crates", cacheKey, text, cratesCacheDuration), "writing crates.io result to cache")
+	}
+
+	return title, text, registryBadgeColor(params[0], text), nil
+}
+
+func (c cratesServiceHandler) fetchVersion(ctx context.Context, name string, opts []string) (string, error) {
+	crate, err := c.fetchCrate(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	if (len(opts) > 0 && opts[0] == "prerelease") || crate.Crate.MaxStableVersion == "" {
+		return crate.Crate.MaxVersion, nil
+	}
+
+	return crate.Crate.MaxStableVersion, nil
+}
+
+func (c cratesServiceHandler) fetchDownloads(ctx context.Context, name string, opts []string) (string, error) {
+	period := "total"
+	if len(opts) > 0 {
+		period = opts[0]
+	}
+
+	crate, err := c.fetchCrate(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	switch period {
+	case "total":
+		return metricFormat(crate.Crate.Downloads), nil
+	case "recent":
+		return metricFormat(crate.Crate.RecentDownloads) + "/90 days", nil
+	default:
+		return "", errors.Errorf("unsupported period %q", period)
+	}
+}
+
+func (c cratesServiceHandler) fetchLicense(ctx context.Context, name string, _ []string) (string, error) {
+	crate, err := c.fetchCrate(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	if v := c.newestVersion(crate); v != nil && v.License != "" {
+		return v.License, nil
+	}
+
+	return "None", nil
+}
+
+func (c cratesServiceHandler) fetchRuntime(ctx context.Context, name string, _ []string) (string, error) {
+	crate, err := c.fetchCrate(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	if v := c.newestVersion(crate); v != nil && v.RustVersion != "" {
+		return ">= " + v.RustVersion, nil
+	}
+
+	return "any", nil
+}
+
+// newestVersion returns the newest non-yanked version of the crate,
+// crates.io orders the versions newest first
+func (cratesServiceHandler) newestVersion(crate *cratesCrate) *cratesVersion {
+	for i := range crate.Versions {
+		if !crate.Versions[i].Yanked {
+			return &crate.Versions[i]
+		}
+	}
+	return nil
+}
+
+func (cratesServiceHandler) fetchCrate(ctx context.Context, crate string) (*cratesCrate, error) {
+	base := strings.TrimRight(configStore.StrDefault(configKeyCratesAPI, cratesDefaultAPI), "/")
+
+	c := &cratesCrate{}
+	req, _ := http.NewRequestWithContext(ctx, "GET", strings.Join([]string{base, "api", "v1", "crates", crate}, "/"), nil)
+	if err := fetchJSON(req, c); err != nil {
+		return nil, errors.Wrap(err, "fetching crate")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+const distroCacheDuration = 60 * time.Minute
+
+// #configStore distro.<distro>.url - string - Override the package API base URL of <distro> (arch, debian, ubuntu, fedora, alpine, homebrew)
+const configKeyDistroURL = "distro.%s.url"
+
+var (
+	distroRevisionSuffix = regexp.MustCompile(`(\+(dfsg|ds|repack)\d*)?(-[^-]*)?$`)
+	distroAPKBuildVer    = regexp.MustCompile(`^pkgver=["']?([^"'\s]+)`)
+
+	distroSources = map[string]distroPackageSource{
+		"arch":     {"Arch Linux", "https://archlinux.org", distroServiceHandler.fetchArch},
+		"debian":   {"Debian", "https://sources.debian.org", distroServiceHandler.fetchDebian},
+		"ubuntu":   {"Ubuntu", "https://api.launchpad.net", distroServiceHandler.fetchUbuntu},
+		"fedora":   {"Fedora", "https://mdapi.fedoraproject.org", distroServiceHandler.fetchFedora},
+		"alpine":   {"Alpine", "https://gitlab.alpinelinux.org", distroServiceHandler.fetchAlpine},
+		"homebrew": {"Homebrew", "https://formulae.brew.sh", distroServiceHandler.fetchHomebrew},
+	}
+)
+
+func init() {
+	registerServiceHandler("distro", distroServiceHandler{})
+}
+
+type distroPackageSource struct {
+	Title      string
+	DefaultURL string
+	// Fetch returns the version of the package in the distro or an
+	// empty string if the distro does not package it. Errors are only
+	// returned when the distro could not be queried.
+	Fetch func(d distroServiceHandler, ctx context.Context, base, pkg string) (string, error)
+}
+
+type distroServiceHandler struct{}
+
+func (distroServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "Distribution packaging status",
+			DemoPath:    "/distro/repos/jq",
+			Arguments:   []string{"repos", "<package>"},
+		},
+		{
+			ServiceName: "Distribution package version",
+			DemoPath:    "/distro/arch/jq",
+			Arguments:   []string{"<arch|debian|ubuntu|fedora|alpine|homebrew>", "<package>"},
+		},
+	}
+}
+
+func (distroServiceHandler) IsEnabled() bool { return true }
+
+func (d distroServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide distro and package")
+		return title, text, color, err
+	}
+
+	if _, ok := distroSources[params[0]]; !ok && params[0] != "repos" {
+		err = fmt.Errorf("unknown distro %q", params[0])
+		return title, text, color, err
+	}
+
+	versions, failed := d.fetchVersions(ctx, params[1])
+	if err = failed[params[0]]; err != nil {
+		return title, text, color, errors.Wrapf(err, "fetching %s package", params[0])
+	}
+
+	if params[0] == "repos" {
+		return "packaged in", fmt.Sprintf("%d repos", len(versions)), colorNameBlue, nil
+	}
+
+	title = distroSources[params[0]].Title
+
+	text, ok := versions[params[0]]
+	if !ok {
+		return title, "not packaged", colorNameLightGray, nil
+	}
+
+	// Packages are compared against the newest version in any of the
+	// distros as the best guess of what upstream has released
+	color = colorNameBrightGreen
+	for _, v := range versions {
+		if compareLooseVersion(v, text) > 0 {
+			color = colorNameRed
+			text += " (outdated)"
+			break
+		}
+	}
+
+	return title, text, color, nil
+}
+
+// fetchVersions queries all distros for the package in parallel and
+// returns the versions of the distros packaging it along with the
+// errors of the distros failed to be queried. Only successful lookups
+// are cached, failed distros are queried again on the next request.
+func (d distroServiceHandler) fetchVersions(ctx context.Context, pkg string) (versions map[string]string, failed map[string]error) {
+	var (
+		lock sync.Mutex
+		wg   sync.WaitGroup
+	)
+
+	versions, failed = map[string]string{}, map[string]error{}
+
+	for name, src := range distroSources {
+		wg.Add(1)
+		go func(name string, src distroPackageSource) {
+			defer wg.Done()
+
+			v, err := cacheStore.Get("distro", name+"/"+pkg)
+			if err != nil {
+				base := strings.TrimRight(configStore.StrDefault(fmt.Sprintf(configKeyDistroURL, name), src.DefaultURL), "/")
+
+				if v, err = src.Fetch(d, ctx, base, pkg); err != nil {
+					// A distro not answering should not break the badges
+					// of the other distros
+					logrus.WithError(err).WithFields(logrus.Fields{"distro": name, "package": pkg}).Debug("fetching distro package")
+
+					lock.Lock()
+					defer lock.Unlock()
+					failed[name] = err
+					return
+				}
+
+				// Packages not found are cached as empty version
+				logErr(cacheStore.Set("distro", name+"/"+pkg, v, distroCacheDuration), "writing distro version to cache")
+			}
+
+			if v == "" {
+				return
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			versions[name] = d.normalizeVersion(v)
+		}(name, src)
+	}
+
+	wg.Wait()
+
+	return versions, failed
+}
+
+// distroNotFound reports whether the error was caused by the distro
+// not knowing the package
+func distroNotFound(err error) bool {
+	var statusErr fetchStatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// normalizeVersion strips the distro specific parts (epoch, package
+// revision, repack markers) from the version to make them comparable
+func (distroServiceHandler) normalizeVersion(v string) string {
+	if idx := strings.Index(v, ":"); idx >= 0 {
+		v = v[idx+1:]
+	}
+	return distroRevisionSuffix.ReplaceAllString(v, "")
+}
+
+func (distroServiceHandler) fetchArch(ctx context.Context, base, pkg string) (string, error) {
+	r := struct {
+		Results []struct {
+			PkgVer string `json:"pkgver"`
+			PkgRel string `json:"pkgrel"`
+		} `json:"results"`
+	}{}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", base+"/packages/search/json/?"+url.Values{"name": []string{pkg}}.Encode(), nil)
+	if err := fetchJSON(req, &r); err != nil {
+		if distroNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	if len(r.Results) == 0 {
+		return "", nil
+	}
+
+	// Package revision is separated by dash to get stripped like
+	// the revisions of the other distros
+	return r.Results[0].PkgVer + "-" + r.Results[0].PkgRel, nil
+}
+
+func (distroServiceHandler) fetchDebian(ctx context.Context, base, pkg string) (string, error) {
+	r := struct {
+		Error    string `json:"error"`
+		Versions []struct {
+			Version string `json:"version"`
+		} `json:"versions"`
+	}{}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", base+"/api/src/"+url.PathEscape(pkg)+"/", nil)
+	if err := fetchJSON(req, &r); err != nil {
+		if distroNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	if r.Error != "" || len(r.Versions) == 0 {
+		return "", nil
+	}
+
+	// Versions are ordered newest first
+	return r.Versions[0].Version, nil
+}
+
+func (distroServiceHandler) fetchUbuntu(ctx context.Context, base, pkg string) (string, error) {
+	r := struct {
+		Entries []struct {
+			Version string `json:"source_package_version"`
+		} `json:"entries"`
+	}{}
+
+	query := url.Values{
+		"ws.op":       []string{"getPublishedSources"},
+		"source_name": []string{pkg},
+		"exact_match": []string{"true"},
+		"status":      []string{"Published"},
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", base+"/1.0/ubuntu/+archive/primary?"+query.Encode(), nil)
+	if err := fetchJSON(req, &r); err != nil {
+		if distroNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	var latest string
+	for _, e := range r.Entries {
+		if latest == "" || compareLooseVersion(e.Version, latest) > 0 {
+			latest = e.Version
+		}
+	}
+
+	return latest, nil
+}
+
+func (distroServiceHandler) fetchFedora(ctx context.Context, base, pkg string) (string, error) {
+	r := struct {
+		Version string `json:"version"`
+	}{}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", base+"/rawhide/pkg/"+url.PathEscape(pkg), nil)
+	if err := fetchJSON(req, &r); err != nil {
+		if distroNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return r.Version, nil
+}
+
+func (distroServiceHandler) fetchAlpine(ctx context.Context, base, pkg string) (string, error) {
+	// There is no package API so the version is read from the APKBUILD
+	// in the aports repository, trying the repositories in order
+	for _, repo := range []string{"main", "community", "testing"} {
+		var version string
+
+		req, _ := http.NewRequestWithContext(ctx, "GET", strings.Join([]string{base, "alpine", "aports", "-", "raw", "master", repo, url.PathEscape(pkg), "APKBUILD"}, "/"), nil)
+		err := fetchDecoded(req, func(body io.Reader) error {
+			scanner := bufio.NewScanner(body)
+			for scanner.Scan() {
+				if m := distroAPKBuildVer.FindStringSubmatch(scanner.Text()); m != nil {
+					version = m[1]
+					break
+				}
+			}
+			return errors.Wrap(scanner.Err(), "reading APKBUILD")
+		})
+
+		switch {
+		case distroNotFound(err):
+			continue
+		case err != nil:
+			return "", err
+		case version != "":
+			return version, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (distroServiceHandler) fetchHomebrew(ctx context.Context, base, pkg string) (string, error) {
+	r := struct {
+		Versions struct {
+			Stable string `json:"stable"`
+		} `json:"versions"`
+		Revision int `json:"revision"`
+	}{}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", base+"/api/formula/"+url.PathEscape(pkg)+".json", nil)
+	if err := fetchJSON(req, &r); err != nil {
+		if distroNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	if r.Versions.Stable == "" {
+		return "", nil
+	}
+
+	return r.Versions.Stable + "-" + strconv.Itoa(r.Revision), nil
+}
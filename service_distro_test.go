@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestDistroVersions(t *testing.T) {
+	var debianUp atomic.Bool
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/packages/search/json/":
+			fmt.Fprint(w, `{"results":[{"pkgver":"1.7.1","pkgrel":"2"}]}`)
+		case "/api/src/jq/":
+			if !debianUp.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, `{"versions":[{"version":"1.6-2.1"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	for name := range distroSources {
+		configStore["distro."+name+".url"] = srv.URL
+		defer delete(configStore, "distro."+name+".url")
+	}
+
+	d := distroServiceHandler{}
+
+	_, text, _, err := d.Handle(context.Background(), []string{"arch", "jq"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1.7.1", text)
+
+	_, text, _, err = d.Handle(context.Background(), []string{"fedora", "jq"})
+	assert.NoError(t, err)
+	assert.Equal(t, "not packaged", text, "404 should be reported as not packaged")
+
+	_, _, _, err = d.Handle(context.Background(), []string{"debian", "jq"})
+	assert.Error(t, err, "failed distro must not be reported as not packaged")
+
+	// The failed lookup must not have been cached
+	debianUp.Store(true)
+	_, text, _, err = d.Handle(context.Background(), []string{"debian", "jq"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1.6 (outdated)", text)
+
+	_, text, _, err = d.Handle(context.Background(), []string{"repos", "jq"})
+	assert.NoError(t, err)
+	assert.Equal(t, "2 repos", text)
+}
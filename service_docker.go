@@ -0,0 +1,211 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const dockerCacheDuration = 30 * time.Minute
+
+func init() {
+	registerServiceHandler("docker", dockerServiceHandler{})
+}
+
+type dockerHubTag struct {
+	Name     string `json:"name"`
+	FullSize int64  `json:"full_size"`
+	Images   []struct {
+		Architecture string `json:"architecture"`
+		Variant      string `json:"variant"`
+		OS           string `json:"os"`
+		Size         int64  `json:"size"`
+	} `json:"images"`
+}
+
+type dockerServiceHandler struct{}
+
+func (dockerServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "Docker Hub pulls",
+			DemoPath:    "/docker/pulls/luzifer/badge-gen",
+			Arguments:   []string{"pulls", "<namespace>", "<repo>"},
+		},
+		{
+			ServiceName: "Docker Hub latest tag",
+			DemoPath:    "/docker/latest-tag/library/alpine",
+			Arguments:   []string{"latest-tag", "<namespace>", "<repo>"},
+		},
+		{
+			ServiceName: "Docker Hub image size",
+			DemoPath:    "/docker/size/library/alpine/latest/arm64",
+			Arguments:   []string{"size", "<namespace>", "<repo>", "[tag]", "[architecture]"},
+		},
+		{
+			ServiceName: "Docker Hub architectures",
+			DemoPath:    "/docker/architectures/library/alpine",
+			Arguments:   []string{"architectures", "<namespace>", "<repo>", "[tag]"},
+		},
+	}
+}
+
+func (dockerServiceHandler) IsEnabled() bool { return true }
+
+func (d dockerServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 3 { //nolint:gomnd
+		err = errors.New("you need to provide command, namespace and repo")
+		return title, text, color, err
+	}
+
+	switch params[0] {
+	case "pulls":
+		title, text, color, err = d.handlePulls(ctx, params[1:])
+	case "latest-tag":
+		title, text, color, err = d.handleLatestTag(ctx, params[1:])
+	case "size":
+		title, text, color, err = d.handleSize(ctx, params[1:])
+	case "architectures":
+		title, text, color, err = d.handleArchitectures(ctx, params[1:])
+	default:
+		err = errors.New("an unknown service command was called")
+	}
+
+	return title, text, color, err
+}
+
+func (d dockerServiceHandler) handlePulls(ctx context.Context, params []string) (title, text, color string, err error) {
+	path := strings.Join([]string{"repositories", params[0], params[1]}, "/") + "/"
+
+	text, err = cacheStore.Get("docker_pulls", path)
+
+	if err != nil {
+		r := struct {
+			PullCount int64 `json:"pull_count"`
+		}{}
+
+		if err = d.fetchAPI(ctx, path, &r); err != nil {
+			return title, text, color, err
+		}
+
+		text = metricFormat(r.PullCount)
+		logErr(cacheStore.Set("docker_pulls", path, text, dockerCacheDuration), "writing Docker Hub pulls to cache")
+	}
+
+	return "docker pulls", text, colorNameBlue, nil
+}
+
+func (d dockerServiceHandler) handleLatestTag(ctx context.Context, params []string) (title, text, color string, err error) {
+	path := strings.Join([]string{"repositories", params[0], params[1], "tags"}, "/") + "?page_size=100&ordering=last_updated"
+
+	text, err = cacheStore.Get("docker_latest_tag", path)
+
+	if err != nil {
+		r := struct {
+			Results []dockerHubTag `json:"results"`
+		}{}
+
+		if err = d.fetchAPI(ctx, path, &r); err != nil {
+			return title, text, color, err
+		}
+
+		tags := make([]string, 0, len(r.Results))
+		for _, t := range r.Results {
+			tags = append(tags, t.Name)
+		}
+
+		switch {
+		case latestVersion(tags, false) != "":
+			text = latestVersion(tags, false)
+		case len(tags) > 0:
+			text = tags[0]
+		default:
+			text = "None"
+		}
+
+		logErr(cacheStore.Set("docker_latest_tag", path, text, dockerCacheDuration), "writing Docker Hub latest tag to cache")
+	}
+
+	return "tag", text, versionColor(text), nil
+}
+
+func (d dockerServiceHandler) handleSize(ctx context.Context, params []string) (title, text, color string, err error) {
+	params = d.defaultTag(params)
+	cacheKey := strings.Join(params, "/")
+
+	text, err = cacheStore.Get("docker_size", cacheKey)
+
+	if err != nil {
+		var tag dockerHubTag
+		if tag, err = d.fetchTag(ctx, params); err != nil {
+			return title, text, color, err
+		}
+
+		size := tag.FullSize
+		if len(params) > 3 { //nolint:gomnd
+			size = -1
+			for _, img := range tag.Images {
+				if params[3] == img.Architecture || params[3] == img.Architecture+"/"+img.Variant {
+					size = img.Size
+					break
+				}
+			}
+
+			if size < 0 {
+				return title, text, color, errors.Errorf("architecture %q not found", params[3])
+			}
+		}
+
+		text = byteFormat(size)
+		logErr(cacheStore.Set("docker_size", cacheKey, text, dockerCacheDuration), "writing Docker Hub image size to cache")
+	}
+
+	return "image size", text, colorNameBlue, nil
+}
+
+func (d dockerServiceHandler) handleArchitectures(ctx context.Context, params []string) (title, text, color string, err error) {
+	params = d.defaultTag(params)
+	cacheKey := strings.Join(params, "/")
+
+	text, err = cacheStore.Get("docker_architectures", cacheKey)
+
+	if err != nil {
+		var tag dockerHubTag
+		if tag, err = d.fetchTag(ctx, params); err != nil {
+			return title, text, color, err
+		}
+
+		archs := map[string]bool{}
+		for _, img := range tag.Images {
+			archs[img.OS+"/"+img.Architecture+"/"+img.Variant] = true
+		}
+
+		text = strconv.Itoa(len(archs))
+		logErr(cacheStore.Set("docker_architectures", cacheKey, text, dockerCacheDuration), "writing Docker Hub architectures to cache")
+	}
+
+	return "architectures", text, colorNameBlue, nil
+}
+
+func (dockerServiceHandler) defaultTag(params []string) []string {
+	if len(params) < 3 { //nolint:gomnd
+		params = append(params, "latest")
+	}
+	return params
+}
+
+func (d dockerServiceHandler) fetchTag(ctx context.Context, params []string) (dockerHubTag, error) {
+	var tag dockerHubTag
+	path := strings.Join([]string{"repositories", params[0], params[1], "tags", params[2]}, "/")
+
+	return tag, d.fetchAPI(ctx, path, &tag)
+}
+
+func (dockerServiceHandler) fetchAPI(ctx context.Context, path string, out interface{}) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://hub.docker.com/v2/"+path, nil)
+	return fetchJSON(req, out)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	dynamicCacheDuration = 5 * time.Minute
+	dynamicMaxBodySize   = 1024 * 1024 // 1MiB
+)
+
+func init() {
+	registerServiceHandler("dynamic", dynamicServiceHandler{})
+}
+
+type dynamicServiceHandler struct{}
+
+func (dynamicServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "Dynamic JSON value",
+			DemoPath:    "/dynamic/json/https%3A%2F%2Fstatus.example.com%2Fapi.json/$.version/version",
+			Arguments:   []string{"json", "<escaped URL>", "<JSONPath>", "<label>", "[prefix]", "[suffix]"},
+		},
+		{
+			ServiceName: "Dynamic YAML value",
+			DemoPath:    "/dynamic/yaml/https%3A%2F%2Fstatus.example.com%2Fstatus.yaml/$.nodes[0].state/node",
+			Arguments:   []string{"yaml", "<escaped URL>", "<JSONPath>", "<label>", "[prefix]", "[suffix]"},
+		},
+		{
+			ServiceName: "Dynamic XML value",
+			DemoPath:    "/dynamic/xml/https%3A%2F%2Fstatus.example.com%2Fstatus.xml/%2F%2Fbuild%2F@number/build",
+			Arguments:   []string{"xml", "<escaped URL>", "<XPath>", "<label>", "[prefix]", "[suffix]"},
+		},
+	}
+}
+
+func (dynamicServiceHandler) IsEnabled() bool { return remoteFetchEnabled() }
+
+func (d dynamicServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 4 { //nolint:gomnd
+		err = errors.New("you need to provide format, URL, query and label")
+		return title, text, color, err
+	}
+
+	var query func([]byte, string) ([]string, error)
+	switch params[0] {
+	case "json":
+		query = d.queryJSON
+	case "yaml":
+		query = d.queryYAML
+	case "xml":
+		query = d.queryXML
+	default:
+		err = fmt.Errorf("unknown format %q", params[0])
+		return title, text, color, err
+	}
+
+	u, err := parseRemoteURL(params[1])
+	if err != nil {
+		return title, text, color, err
+	}
+
+	cacheKey := strings.Join(params[:3], "/")
+	text, err = cacheStore.Get("dynamic", cacheKey)
+
+	if err != nil {
+		req, _ := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+
+		var body []byte
+		if err = fetchDecodedWith(remoteHTTPClient, req, func(r io.Reader) (err error) {
+			body, err = io.ReadAll(io.LimitReader(r, dynamicMaxBodySize))
+			return errors.Wrap(err, "reading response")
+		}); err != nil {
+			return title, text, color, err
+		}
+
+		values, err := query(body, params[2])
+		if err != nil {
+			return title, text, color, err
+		}
+
+		if len(values) == 0 {
+			return params[3], "not found", colorNameLightGray, nil
+		}
+		text = strings.Join(values, ", ")
+
+		logErr(cacheStore.Set("dynamic", cacheKey, text, dynamicCacheDuration), "writing dynamic value to cache")
+	}
+
+	if len(params) > 4 { //nolint:gomnd
+		text = params[4] + text
+	}
+	if len(params) > 5 { //nolint:gomnd
+		text += params[5]
+	}
+
+	return params[3], text, colorNameBlue, nil
+}
+
+func (dynamicServiceHandler) queryJSON(body []byte, expr string) ([]string, error) {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, errors.Wrap(err, "parsing JSON")
+	}
+
+	return formatQueryResult(queryJSONPath(doc, expr))
+}
+
+func (dynamicServiceHandler) queryYAML(body []byte, expr string) ([]string, error) {
+	var doc any
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		return nil, errors.Wrap(err, "parsing YAML")
+	}
+
+	return formatQueryResult(queryJSONPath(normalizeYAML(doc), expr))
+}
+
+func (dynamicServiceHandler) queryXML(body []byte, expr string) ([]string, error) {
+	doc, err := parseXMLTree(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	return queryXPath(doc, expr)
+}
+
+func formatQueryResult(values []any, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		out = append(out, formatQueryValue(v))
+	}
+
+	return out, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	endpointCacheDuration    = 5 * time.Minute
+	endpointMinCacheDuration = 30 * time.Second
+	endpointMaxCacheDuration = 24 * time.Hour
+)
+
+func init() {
+	registerServiceHandler("endpoint", endpointServiceHandler{})
+}
+
+type (
+	endpointServiceHandler struct{}
+
+	// endpointDescriptor is the shields.io compatible badge description
+	// served by the endpoint. Style and logo are not supported by the
+	// renderer and therefore ignored, the cacheSeconds are limited to
+	// the range of endpointMinCacheDuration to endpointMaxCacheDuration.
+	endpointDescriptor struct {
+		SchemaVersion int    `json:"schemaVersion"`
+		Label         string `json:"label"`
+		Message       string `json:"message"`
+		Color         string `json:"color"`
+		LabelColor    string `json:"labelColor"`
+		Style         string `json:"style"`
+		Logo          string `json:"logo"`
+		CacheSeconds  int64  `json:"cacheSeconds"`
+		IsError       bool   `json:"isError"`
+	}
+)
+
+func (endpointServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{{
+		ServiceName: "Endpoint badge",
+		DemoPath:    "/endpoint/https%3A%2F%2Fstatus.example.com%2Fbadge.json",
+		Arguments:   []string{"<escaped URL>"},
+	}}
+}
+
+func (endpointServiceHandler) IsEnabled() bool { return remoteFetchEnabled() }
+
+func (e endpointServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	desc, err := e.fetchDescriptor(ctx, params)
+	if err != nil {
+		return title, text, color, err
+	}
+
+	return desc.Label, desc.Message, desc.messageColor(), nil
+}
+
+// HandleSegments renders the badge using the label color given by
+// the endpoint
+func (e endpointServiceHandler) HandleSegments(ctx context.Context, params []string) ([]badgeSegment, error) {
+	desc, err := e.fetchDescriptor(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := titleTextSegments(desc.Label, desc.Message, desc.messageColor())
+	if desc.LabelColor != "" {
+		segments[0].Color = desc.LabelColor
+	}
+
+	return segments, nil
+}
+
+// fetchDescriptor retrieves the descriptor from the cache or the
+// endpoint given in the parameters
+func (endpointServiceHandler) fetchDescriptor(ctx context.Context, params []string) (desc endpointDescriptor, err error) {
+	if len(params) < 1 || params[0] == "" {
+		return desc, errors.New("you need to provide the endpoint URL")
+	}
+
+	u, err := parseRemoteURL(params[0])
+	if err != nil {
+		return desc, err
+	}
+
+	if cached, err := cacheStore.Get("endpoint", u.String()); err == nil {
+		return desc, errors.Wrap(json.Unmarshal([]byte(cached), &desc), "decoding cached descriptor")
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err = fetchJSONWith(remoteHTTPClient, req, &desc); err != nil {
+		return desc, err
+	}
+
+	if err = desc.validate(); err != nil {
+		return desc, err
+	}
+
+	data, err := json.Marshal(desc)
+	if err != nil {
+		return desc, errors.Wrap(err, "encoding descriptor")
+	}
+	logErr(cacheStore.Set("endpoint", u.String(), string(data), desc.cacheDuration()), "writing endpoint descriptor to cache")
+
+	return desc, nil
+}
+
+func (e endpointDescriptor) validate() error {
+	if e.SchemaVersion != 1 {
+		return errors.Errorf("unsupported schemaVersion %d", e.SchemaVersion)
+	}
+
+	if e.Message == "" {
+		return errors.New("endpoint did not provide a message")
+	}
+
+	return nil
+}
+
+// cacheDuration returns the cacheSeconds requested by the endpoint
+// limited to a sane range
+func (e endpointDescriptor) cacheDuration() time.Duration {
+	if e.CacheSeconds <= 0 {
+		return endpointCacheDuration
+	}
+
+	ttl := time.Duration(min(e.CacheSeconds, int64(endpointMaxCacheDuration/time.Second))) * time.Second
+	return max(ttl, endpointMinCacheDuration)
+}
+
+func (e endpointDescriptor) messageColor() string {
+	switch {
+	case e.Color != "":
+		return e.Color
+	case e.IsError:
+		return colorNameRed
+	default:
+		return colorNameLightGray
+	}
+}
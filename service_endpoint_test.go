@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointDescriptorValidate(t *testing.T) {
+	cases := []struct {
+		Desc  endpointDescriptor
+		Error string
+	}{
+		{endpointDescriptor{SchemaVersion: 1, Message: "passing"}, ""},
+		{endpointDescriptor{SchemaVersion: 2, Message: "passing"}, "unsupported schemaVersion 2"},
+		{endpointDescriptor{Message: "passing"}, "unsupported schemaVersion 0"},
+		{endpointDescriptor{SchemaVersion: 1}, "endpoint did not provide a message"},
+	}
+
+	for _, c := range cases {
+		err := c.Desc.validate()
+		if c.Error == "" {
+			assert.NoError(t, err)
+			continue
+		}
+		assert.EqualError(t, err, c.Error)
+	}
+}
+
+func TestEndpointDescriptorDefaults(t *testing.T) {
+	assert.Equal(t, colorNameLightGray, endpointDescriptor{}.messageColor())
+	assert.Equal(t, colorNameRed, endpointDescriptor{IsError: true}.messageColor())
+	assert.Equal(t, "blue", endpointDescriptor{IsError: true, Color: "blue"}.messageColor())
+
+	assert.Equal(t, endpointCacheDuration, endpointDescriptor{}.cacheDuration())
+	assert.Equal(t, endpointMinCacheDuration, endpointDescriptor{CacheSeconds: 1}.cacheDuration())
+	assert.Equal(t, 10*time.Minute, endpointDescriptor{CacheSeconds: 600}.cacheDuration())
+	assert.Equal(t, endpointMaxCacheDuration, endpointDescriptor{CacheSeconds: 1 << 62}.cacheDuration())
+}
+
+func TestHttpEndpointBadge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/error.json":
+			_, _ = w.Write([]byte(`{"schemaVersion":1,"label":"build","message":"failing","isError":true}`))
+		default:
+			_, _ = w.Write([]byte(`{"schemaVersion":1,"label":"build","message":"passing","color":"blue","labelColor":"orange"}`))
+		}
+	}))
+	defer srv.Close()
+
+	configStore[configKeyRemoteAllowedHosts] = []interface{}{"127.0.0.1"}
+	defer delete(configStore, configKeyRemoteAllowedHosts)
+
+	for path, expect := range map[string][]string{
+		"/badge.json": {">passing</text>", "#007ec6", "#fe7d37"},
+		"/error.json": {">failing</text>", "#e05d44", "#555"},
+	} {
+		resp, p := doRequest(t, "GET", "/endpoint/"+url.QueryEscape(srv.URL+path), nil, nil)
+		assert.Equal(t, http.StatusOK, resp.Code, path)
+		for _, e := range expect {
+			assert.Contains(t, p, e, path)
+		}
+	}
+}
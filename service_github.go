@@ -29,7 +29,13 @@ type githubAsset struct {
 }
 
 type githubRepo struct {
-	StargazersCount int64 `json:"stargazers_count"`
+	DefaultBranch   string `json:"default_branch"`
+	StargazersCount int64  `json:"stargazers_count"`
+}
+
+type githubCheckRun struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
 }
 
 type githubServiceHandler struct{}
@@ -66,6 +72,11 @@ func (githubServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
 			DemoPath:    "/github/downloads/atom/atom/v1.8.0/atom-amd64.deb",
 			Arguments:   []string{"downloads", "<user>", "<repo>", "<tag or \"latest\">", "<asset>"},
 		},
+		{
+			ServiceName: "GitHub check runs",
+			DemoPath:    "/github/checks/Luzifer/badge-gen",
+			Arguments:   []string{"checks", "<user>", "<repo>", "[ref]"},
+		},
 		{
 			ServiceName: "Github stars by repository",
 			DemoPath:    "/github/stars/atom/atom",
@@ -93,6 +104,8 @@ func (g githubServiceHandler) Handle(ctx context.Context, params []string) (titl
 		title, text, color, err = g.handleDownloads(ctx, params[1:])
 	case "stars":
 		title, text, color, err = g.handleStargazers(ctx, params[1:])
+	case "checks":
+		title, text, color, err = g.handleChecks(ctx, params[1:])
 	default:
 		err = errors.New("an unknown service command was called")
 	}
@@ -100,6 +113,138 @@ func (g githubServiceHandler) Handle(ctx context.Context, params []string) (titl
 	return title, text, color, err
 }
 
+// HandleSegments renders the check runs as one segment per run and
+// all other commands through Handle
+func (g githubServiceHandler) HandleSegments(ctx context.Context, params []string) ([]badgeSegment, error) {
+	if len(params) < 3 || params[0] != "checks" { //nolint:gomnd
+		title, text, color, err := g.Handle(ctx, params)
+		return titleTextSegments(title, text, color), err
+	}
+
+	runs, err := g.fetchCheckRuns(ctx, params[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(runs) == 0 {
+		return titleTextSegments("checks", ciStatusUnknown, ciStatusColor(ciStatusUnknown)), nil
+	}
+
+	segments := []badgeSegment{{Text: "checks", Color: defaultLabelColor}}
+	for _, run := range runs {
+		text := run.Name + " " + run.State
+		switch run.State {
+		case "passed":
+			text = run.Name + " ✓"
+		case "failed":
+			text = run.Name + " ✗"
+		}
+
+		segments = append(segments, badgeSegment{Text: text, Color: ciStatusColor(run.State)})
+	}
+
+	return segments, nil
+}
+
+func (g githubServiceHandler) handleChecks(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("Unsupported number of arguments")
+		return title, text, color, err
+	}
+
+	runs, err := g.fetchCheckRuns(ctx, params)
+	if err != nil {
+		return title, text, color, err
+	}
+
+	// Summarize the runs by their most important state
+	text = ciStatusUnknown
+	for _, state := range []string{"failed", "running", "pending", "passed"} {
+		for _, run := range runs {
+			if run.State == state {
+				text = state
+				break
+			}
+		}
+		if text != ciStatusUnknown {
+			break
+		}
+	}
+
+	return "checks", text, ciStatusColor(text), nil
+}
+
+func (g githubServiceHandler) fetchCheckRuns(ctx context.Context, params []string) ([]githubCheckRun, error) {
+	var runs []githubCheckRun
+
+	cacheKey := strings.Join(params, "/")
+	if cached, err := cacheStore.Get("github_checks", cacheKey); err == nil {
+		return runs, errors.Wrap(json.Unmarshal([]byte(cached), &runs), "decoding cached check runs")
+	}
+
+	ref := ""
+	if len(params) > 2 { //nolint:gomnd
+		ref = params[2]
+	} else {
+		repo := githubRepo{}
+		if err := g.fetchAPI(ctx, strings.Join([]string{"repos", params[0], params[1]}, "/"), nil, &repo); err != nil {
+			return nil, err
+		}
+		ref = repo.DefaultBranch
+	}
+
+	r := struct {
+		CheckRuns []struct {
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+		} `json:"check_runs"`
+	}{}
+
+	path := strings.Join([]string{"repos", params[0], params[1], "commits", ref, "check-runs"}, "/") + "?per_page=100"
+	if err := g.fetchAPI(ctx, path, nil, &r); err != nil {
+		return nil, err
+	}
+
+	for _, run := range r.CheckRuns {
+		runs = append(runs, githubCheckRun{Name: run.Name, State: githubCheckState(run.Status, run.Conclusion)})
+	}
+
+	cached, err := json.Marshal(runs)
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding check runs")
+	}
+	logErr(cacheStore.Set("github_checks", cacheKey, string(cached), githubCacheDuration), "writing Github check runs to cache")
+
+	return runs, nil
+}
+
+// githubCheckState maps status and conclusion of a check run onto the
+// states known to ciStatusColor
+func githubCheckState(status, conclusion string) string {
+	switch status {
+	case "queued", "requested", "waiting", "pending":
+		return "pending"
+	case "in_progress":
+		return "running"
+	}
+
+	switch conclusion {
+	case "success":
+		return "passed"
+	case "failure", "timed_out", "startup_failure":
+		return "failed"
+	case "cancelled", "stale":
+		return "canceled"
+	case "skipped", "neutral":
+		return "skipped"
+	case "action_required":
+		return "blocked"
+	default:
+		return ciStatusUnknown
+	}
+}
+
 func (g githubServiceHandler) handleStargazers(ctx context.Context, params []string) (title, text, color string, err error) {
 	path := strings.Join([]string{"repos", params[0], params[1]}, "/")
 
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/net/context"
+)
+
+const (
+	gomodCacheDuration   = 30 * time.Minute
+	gomodDefaultProxy    = "https://proxy.golang.org"
+	gomodMaxLicenseSize  = 64 * 1024        // 64KiB
+	gomodMaxModFileSize  = 1024 * 1024      // 1MiB
+	gomodMaxModuleZip    = 32 * 1024 * 1024 // 32MiB
+	gomodMaxMajorProbing = 20
+	gomodMajorProbeBatch = 4
+	// gomodMaxZipDownloads limits the module zips downloaded at the
+	// same time to scan them for their license
+	gomodMaxZipDownloads = 2
+)
+
+// errGomodTooLarge is returned by fetchProxy when the response exceeds
+// the given maximum size
+var errGomodTooLarge = errors.New("response too large")
+
+// gomodZipDownloads holds a slot for every module zip being downloaded
+var gomodZipDownloads = make(chan struct{}, gomodMaxZipDownloads)
+
+// #configStore gomod.proxy - string - Base URL of the GOPROXY compatible module proxy (defaults to https://proxy.golang.org)
+const configKeyGomodProxy = "gomod.proxy"
+
+func init() {
+	registerServiceHandler("gomod", gomodServiceHandler{})
+}
+
+type gomodServiceHandler struct{}
+
+func (gomodServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "Go module version",
+			DemoPath:    "/gomod/version/github.com/Luzifer/rconfig",
+			Arguments:   []string{"version[:prerelease]", "<module path...>"},
+		},
+		{
+			ServiceName: "Go module go directive",
+			DemoPath:    "/gomod/go/github.com/Luzifer/rconfig/v2",
+			Arguments:   []string{"go", "<module path...>[@version]"},
+		},
+		{
+			ServiceName: "Go module license",
+			DemoPath:    "/gomod/license/github.com/Luzifer/rconfig/v2",
+			Arguments:   []string{"license", "<module path...>[@version]"},
+		},
+	}
+}
+
+func (gomodServiceHandler) IsEnabled() bool { return true }
+
+func (g gomodServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide command and module path")
+		return title, text, color, err
+	}
+
+	command, option, _ := strings.Cut(params[0], ":")
+	modPath, modVersion, _ := strings.Cut(strings.Join(params[1:], "/"), "@")
+
+	if err = module.CheckPath(modPath); err != nil {
+		return title, text, color, errors.Wrap(err, "checking module path")
+	}
+
+	switch command {
+	case "version":
+		title = "go module"
+	case "go":
+		title = "go"
+	case "license":
+		title = "license"
+	default:
+		err = errors.New("an unknown service command was called")
+		return title, text, color, err
+	}
+
+	cacheKey := strings.Join(params, "/")
+	text, err = cacheStore.Get("gomod", cacheKey)
+
+	if err != nil {
+		switch command {
+		case "version":
+			text, err = g.fetchLatestMajorVersion(ctx, modPath, option == "prerelease")
+
+		case "go":
+			text, err = g.fetchForVersion(ctx, modPath, modVersion, g.fetchGoDirective)
+
+		case "license":
+			text, err = g.fetchForVersion(ctx, modPath, modVersion, g.fetchLicense)
+		}
+
+		if err != nil {
+			return title, text, color, err
+		}
+
+		logErr(cacheStore.Set("gomod", cacheKey, text, gomodCacheDuration), "writing Go module result to cache")
+	}
+
+	color = colorNameBlue
+	if command == "version" {
+		color = versionColor(text)
+	}
+
+	return title, text, color, nil
+}
+
+// fetchLatestMajorVersion resolves the latest version of the module
+// and afterwards probes for higher major versions (which live under
+// a "/vN" suffixed module path) to find the latest version overall
+func (g gomodServiceHandler) fetchLatestMajorVersion(ctx context.Context, modPath string, prerelease bool) (string, error) {
+	latest, err := g.fetchLatestVersion(ctx, modPath, prerelease)
+	if err != nil {
+		return "", err
+	}
+
+	prefix, pathMajor, ok := module.SplitPathVersion(modPath)
+	if !ok || strings.HasPrefix(pathMajor, ".") {
+		// gopkg.in paths have their own major version semantics
+		return latest, nil
+	}
+
+	major := 1
+	if pathMajor != "" {
+		if major, err = strconv.Atoi(strings.TrimPrefix(pathMajor, "/v")); err != nil {
+			return "", errors.Wrap(err, "parsing major version")
+		}
+	}
+
+	// Major versions are probed concurrently in batches to keep the
+	// probing within the badge generation timeout
+	for probed := 0; probed < gomodMaxMajorProbing; probed += gomodMajorProbeBatch {
+		var (
+			results = make([]string, gomodMajorProbeBatch)
+			wg      sync.WaitGroup
+		)
+
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if v, err := g.fetchLatestVersion(ctx, fmt.Sprintf("%s/v%d", prefix, major+1+i), prerelease); err == nil {
+					results[i] = v
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		for _, v := range results {
+			if v == "" {
+				// Proxy responds with 404 / 410 for unknown modules,
+				// there are no higher majors after the first miss
+				return latest, nil
+			}
+			latest = v
+			major++
+		}
+	}
+
+	return latest, nil
+}
+
+// fetchLatestVersion resolves the latest tagged version of the
+// module path using the same precedence as the go command: release
+// versions before prereleases before pseudo-versions
+func (g gomodServiceHandler) fetchLatestVersion(ctx context.Context, modPath string, prerelease bool) (string, error) {
+	list, err := g.fetchProxy(ctx, modPath, "@v/list", gomodMaxModFileSize)
+	if err != nil {
+		return "", errors.Wrap(err, "fetching version list")
+	}
+
+	versions := strings.Fields(string(list))
+	if v := latestVersion(versions, prerelease); v != "" {
+		return v, nil
+	}
+	if v := latestVersion(versions, true); v != "" {
+		return v, nil
+	}
+
+	info := struct {
+		Version string `json:"Version"`
+	}{}
+
+	req, err := g.newProxyRequest(ctx, modPath, "@latest")
+	if err != nil {
+		return "", err
+	}
+
+	if err = fetchJSON(req, &info); err != nil {
+		return "", errors.Wrap(err, "fetching latest version")
+	}
+
+	return info.Version, nil
+}
+
+// fetchForVersion executes the fetch for the given version of the
+// module or for its latest version if none was given
+func (g gomodServiceHandler) fetchForVersion(
+	ctx context.Context, modPath, modVersion string,
+	fetch func(context.Context, string, string) (string, error),
+) (string, error) {
+	if modVersion == "" {
+		var err error
+		if modVersion, err = g.fetchLatestVersion(ctx, modPath, false); err != nil {
+			return "", err
+		}
+	}
+
+	return fetch(ctx, modPath, modVersion)
+}
+
+func (g gomodServiceHandler) fetchGoDirective(ctx context.Context, modPath, modVersion string) (string, error) {
+	escVersion, err := module.EscapeVersion(modVersion)
+	if err != nil {
+		return "", errors.Wrap(err, "escaping version")
+	}
+
+	data, err := g.fetchProxy(ctx, modPath, "@v/"+escVersion+".mod", gomodMaxModFileSize)
+	if err != nil {
+		return "", errors.Wrap(err, "fetching go.mod")
+	}
+
+	f, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing go.mod")
+	}
+
+	if f.Go == nil {
+		return "unknown", nil
+	}
+
+	return f.Go.Version, nil
+}
+
+func (g gomodServiceHandler) fetchLicense(ctx context.Context, modPath, modVersion string) (string, error) {
+	escVersion, err := module.EscapeVersion(modVersion)
+	if err != nil {
+		return "", errors.Wrap(err, "escaping version")
+	}
+
+	select {
+	case gomodZipDownloads <- struct{}{}:
+		defer func() { <-gomodZipDownloads }()
+	case <-ctx.Done():
+		return "", errors.Wrap(ctx.Err(), "waiting for module zip download")
+	}
+
+	// Module zips are spooled to disk instead of being held in memory
+	// as only the license file is of interest
+	tmp, err := os.CreateTemp("", "badge-gen-gomod-*.zip")
+	if err != nil {
+		return "", errors.Wrap(err, "creating temporary file")
+	}
+	defer func() {
+		logErr(tmp.Close(), "closing temporary module zip")
+		logErr(os.Remove(tmp.Name()), "removing temporary module zip")
+	}()
+
+	size, err := g.fetchProxyTo(ctx, modPath, "@v/"+escVersion+".zip", gomodMaxModuleZip, tmp)
+	switch {
+	case errors.Is(err, errGomodTooLarge):
+		return "", errors.Errorf("module too large (exceeds %s)", byteFormat(gomodMaxModuleZip))
+	case err != nil:
+		return "", errors.Wrap(err, "fetching module zip")
+	}
+
+	zr, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return "", errors.Wrap(err, "opening module zip")
+	}
+
+	// Files are stored as <module>@<version>/<path> inside the zip
+	root := modPath + "@" + modVersion + "/"
+	for _, f := range zr.File {
+		name := strings.TrimPrefix(f.Name, root)
+		if strings.Contains(name, "/") {
+			continue
+		}
+
+		lname := strings.ToLower(name)
+		if !strings.HasPrefix(lname, "license") && !strings.HasPrefix(lname, "licence") && !strings.HasPrefix(lname, "copying") {
+			continue
+		}
+
+		fr, err := f.Open()
+		if err != nil {
+			return "", errors.Wrap(err, "opening license file")
+		}
+
+		text, err := io.ReadAll(io.LimitReader(fr, gomodMaxLicenseSize))
+		logErr(fr.Close(), "closing license file")
+		if err != nil {
+			return "", errors.Wrap(err, "reading license file")
+		}
+
+		if l := detectLicense(string(text)); l != "" {
+			return l, nil
+		}
+		return "unknown", nil
+	}
+
+	return "None", nil
+}
+
+func (g gomodServiceHandler) fetchProxy(ctx context.Context, modPath, path string, maxSize int64) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	_, err := g.fetchProxyTo(ctx, modPath, path, maxSize, buf)
+	return buf.Bytes(), err
+}
+
+// fetchProxyTo writes the response of the proxy to w and returns its
+// size or errGomodTooLarge if it exceeds maxSize
+func (g gomodServiceHandler) fetchProxyTo(ctx context.Context, modPath, path string, maxSize int64, w io.Writer) (size int64, err error) {
+	req, err := g.newProxyRequest(ctx, modPath, path)
+	if err != nil {
+		return 0, err
+	}
+
+	err = fetchDecoded(req, func(body io.Reader) (err error) {
+		// Reading one byte more than allowed detects responses cut off
+		// by the limit which would fail to parse otherwise
+		if size, err = io.Copy(w, io.LimitReader(body, maxSize+1)); err != nil {
+			return errors.Wrap(err, "reading response")
+		}
+
+		if size > maxSize {
+			return errGomodTooLarge
+		}
+
+		return nil
+	})
+
+	return size, err
+}
+
+func (gomodServiceHandler) newProxyRequest(ctx context.Context, modPath, path string) (*http.Request, error) {
+	escPath, err := module.EscapePath(modPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "escaping module path")
+	}
+
+	base := strings.TrimRight(configStore.StrDefault(configKeyGomodProxy, gomodDefaultProxy), "/")
+	req, _ := http.NewRequestWithContext(ctx, "GET", strings.Join([]string{base, escPath, path}, "/"), nil)
+
+	return req, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestGomodProxy(t *testing.T) {
+	modZip := new(bytes.Buffer)
+	zw := zip.NewWriter(modZip)
+	for name, content := range map[string]string{
+		"example.com/mod@v1.0.0/LICENSE": "Permission is hereby granted, free of charge, to any person obtaining a copy of this software ... " +
+			"The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.",
+		"example.com/mod@v1.0.0/sub/COPYING": "GNU GENERAL PUBLIC LICENSE",
+		"example.com/mod@v1.0.0/main.go":     "package main",
+	} {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = f.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/example.com/mod/@v/list":
+			_, _ = w.Write([]byte("v1.0.0\nv1.2.0\n"))
+		case "/example.com/mod/v2/@v/list":
+			_, _ = w.Write([]byte("v2.0.0\n"))
+		case "/example.com/mod/v3/@v/list":
+			_, _ = w.Write([]byte("v3.1.0\n"))
+		case "/example.com/mod/@v/v1.0.0.zip":
+			_, _ = w.Write(modZip.Bytes())
+		case "/example.com/mod/@v/v1.2.0.zip":
+			_, _ = w.Write([]byte(strings.Repeat("x", gomodMaxModuleZip+1)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	configStore[configKeyGomodProxy] = srv.URL
+	defer delete(configStore, configKeyGomodProxy)
+
+	g := gomodServiceHandler{}
+
+	v, err := g.fetchLatestMajorVersion(context.Background(), "example.com/mod", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "v3.1.0", v, "latest major should be found")
+
+	l, err := g.fetchLicense(context.Background(), "example.com/mod", "v1.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "MIT", l, "license in the module root should be detected")
+
+	_, err = g.fetchLicense(context.Background(), "example.com/mod", "v1.2.0")
+	assert.ErrorContains(t, err, "module too large")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+func init() {
+	registerServiceHandler("history", historyServiceHandler{})
+}
+
+type historyServiceHandler struct{}
+
+func (historyServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "Value trend",
+			DemoPath:    "/history/delta:week/aur/votes/yay",
+			Arguments:   []string{"delta[:day|week|month]", "<service>", "<parameters...>"},
+		},
+		{
+			ServiceName: "Value sparkline",
+			DemoPath:    "/history/sparkline:month/aur/votes/yay",
+			Arguments:   []string{"sparkline[:day|week|month]", "<service>", "<parameters...>"},
+		},
+	}
+}
+
+func (historyServiceHandler) IsEnabled() bool { return true }
+
+func (historyServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide mode and service")
+		return title, text, color, err
+	}
+
+	mode, periodName, _ := strings.Cut(params[0], ":")
+	if periodName == "" {
+		periodName = "week"
+	}
+
+	period, ok := historyPeriods[periodName]
+	if !ok {
+		err = fmt.Errorf("unknown period %q", periodName)
+		return title, text, color, err
+	}
+
+	if mode != "delta" && mode != "sparkline" {
+		err = errors.New("an unknown service command was called")
+		return title, text, color, err
+	}
+
+	handler, ok := serviceHandlers[params[1]]
+	if !ok || !handler.IsEnabled() || params[1] == "history" {
+		err = fmt.Errorf("unknown service %q", params[1])
+		return title, text, color, err
+	}
+
+	if title, text, color, err = handler.Handle(ctx, params[2:]); err != nil {
+		return title, text, color, err
+	}
+
+	value, ok := parseBadgeNumber(text)
+	if !ok {
+		err = fmt.Errorf("value %q is not numeric", text)
+		return title, text, color, err
+	}
+
+	now := time.Now()
+	points := historySince(recordHistory(historyKey(params[1], params[2:]), value, now), now.Add(-period))
+
+	if mode == "sparkline" {
+		return title, text + " " + historySparkline(points), color, nil
+	}
+
+	return title, fmt.Sprintf("%s %s %s this %s", text, historyTrend(points), historyDelta(points), periodName), color, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	httpStatusCacheDuration = time.Minute
+	httpCertCacheDuration   = time.Hour
+	httpCertPort            = "443"
+)
+
+func init() {
+	registerServiceHandler("http", httpServiceHandler{})
+}
+
+type httpServiceHandler struct{}
+
+func (httpServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "Website status",
+			DemoPath:    "/http/status/https%3A%2F%2Fstatus.example.com%2Fhealthz",
+			Arguments:   []string{"status", "<escaped URL>"},
+		},
+		{
+			ServiceName: "TLS certificate expiry",
+			DemoPath:    "/http/cert/status.example.com",
+			Arguments:   []string{"cert", "<host>"},
+		},
+	}
+}
+
+func (httpServiceHandler) IsEnabled() bool { return remoteFetchEnabled() }
+
+func (h httpServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide command and target")
+		return title, text, color, err
+	}
+
+	switch params[0] {
+	case "status":
+		return h.handleStatus(ctx, params[1])
+	case "cert":
+		return h.handleCert(ctx, params[1])
+	default:
+		err = errors.New("an unknown service command was called")
+		return title, text, color, err
+	}
+}
+
+func (httpServiceHandler) handleStatus(ctx context.Context, target string) (title, text, color string, err error) {
+	u, err := parseRemoteURL(target)
+	if err != nil {
+		return title, text, color, err
+	}
+
+	title = "status"
+
+	text, err = cacheStore.Get("http_status", u.String())
+	if err != nil {
+		var (
+			resp    *http.Response
+			elapsed time.Duration
+		)
+
+		// Not all servers implement HEAD so the body is requested in
+		// case they reject the method
+		for _, method := range []string{http.MethodHead, http.MethodGet} {
+			req, _ := http.NewRequestWithContext(ctx, method, u.String(), nil)
+			req.Header.Set("User-Agent", "badge-gen/"+version)
+
+			start := time.Now()
+			if resp, err = remoteHTTPClient.Do(req); err != nil {
+				break
+			}
+			elapsed = time.Since(start)
+			logErr(resp.Body.Close(), "closing response body (leaked fd)")
+
+			if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+				break
+			}
+		}
+
+		if ctx.Err() != nil {
+			// The request was cancelled by the client or timed out on
+			// our side which tells nothing about the target
+			return title, text, color, errors.Wrap(ctx.Err(), "checking status")
+		}
+
+		switch {
+		case err != nil:
+			text = "down"
+		case resp.StatusCode >= http.StatusBadRequest:
+			text = fmt.Sprintf("down | %d", resp.StatusCode)
+		default:
+			text = fmt.Sprintf("up | %d | %dms", resp.StatusCode, elapsed.Milliseconds())
+		}
+
+		logErr(cacheStore.Set("http_status", u.String(), text, httpStatusCacheDuration), "writing HTTP status to cache")
+	}
+
+	color = colorNameRed
+	if strings.HasPrefix(text, "up") {
+		color = colorNameBrightGreen
+	}
+
+	return title, text, color, nil
+}
+
+func (httpServiceHandler) handleCert(ctx context.Context, target string) (title, text, color string, err error) {
+	// Only the HTTPS port is checked to prevent the badge from being
+	// used to probe other services on the allowed hosts
+	host, port, err := net.SplitHostPort(target)
+	switch {
+	case err != nil:
+		host, port = target, httpCertPort
+	case port != httpCertPort:
+		err = errors.Errorf("only port %s is supported", httpCertPort)
+		return title, text, color, err
+	}
+
+	if !remoteHostAllowed(host) {
+		err = fmt.Errorf("host %q is not allowed", host)
+		return title, text, color, err
+	}
+
+	title = "certificate"
+	addr := net.JoinHostPort(host, port)
+
+	notAfterStr, err := cacheStore.Get("http_cert", addr)
+	if err != nil {
+		// Verification is skipped as expired or otherwise invalid
+		// certificates are exactly what the badge should report
+		dialer := &tls.Dialer{Config: &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: true, //nolint:gosec
+		}}
+
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return title, "unreachable", colorNameRed, nil
+		}
+
+		certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+		logErr(conn.Close(), "closing TLS connection")
+
+		if len(certs) == 0 {
+			return title, text, color, errors.New("server did not present a certificate")
+		}
+
+		notAfterStr = strconv.FormatInt(certs[0].NotAfter.Unix(), 10)
+		logErr(cacheStore.Set("http_cert", addr, notAfterStr, httpCertCacheDuration), "writing certificate expiry to cache")
+	}
+
+	notAfter, err := strconv.ParseInt(notAfterStr, 10, 64)
+	if err != nil {
+		return title, text, color, errors.Wrap(err, "parsing certificate expiry")
+	}
+
+	text, color = certExpiryBadge(time.Until(time.Unix(notAfter, 0)))
+	return title, text, color, nil
+}
+
+// certExpiryBadge formats the remaining validity of a certificate in
+// days, getting more alarming the closer the expiry is
+func certExpiryBadge(remaining time.Duration) (text, color string) {
+	days := int(remaining / (24 * time.Hour)) //nolint:gomnd
+
+	switch {
+	case remaining <= 0:
+		return "expired", colorNameRed
+	case days < 7: //nolint:gomnd
+		color = colorNameRed
+	case days < 14: //nolint:gomnd
+		color = colorNameOrange
+	case days < 30: //nolint:gomnd
+		color = colorNameYellow
+	default:
+		color = colorNameBrightGreen
+	}
+
+	if days == 1 {
+		return "1 day", color
+	}
+	return fmt.Sprintf("%d days", days), color
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+		case "/broken":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	configStore[configKeyRemoteAllowedHosts] = []interface{}{"127.0.0.1"}
+	defer delete(configStore, configKeyRemoteAllowedHosts)
+
+	h := httpServiceHandler{}
+
+	// A cancelled request must neither be reported nor cached as down
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, _, err := h.handleStatus(ctx, srv.URL+"/ok")
+	assert.Error(t, err)
+
+	for path, expect := range map[string]*regexp.Regexp{
+		"/ok":      regexp.MustCompile(`^up \| 200 \| \d+ms$`),
+		"/no-head": regexp.MustCompile(`^up \| 200 \| \d+ms$`),
+		"/broken":  regexp.MustCompile(`^down \| 503$`),
+	} {
+		_, text, color, err := h.handleStatus(context.Background(), srv.URL+path)
+		if !assert.NoError(t, err, path) {
+			continue
+		}
+
+		assert.Regexp(t, expect, text, path)
+		if path == "/broken" {
+			assert.Equal(t, colorNameRed, color, path)
+		} else {
+			assert.Equal(t, colorNameBrightGreen, color, path)
+		}
+	}
+
+	_, _, _, err = h.handleStatus(context.Background(), "http://example.com/")
+	assert.EqualError(t, err, `host "example.com" is not allowed`)
+}
+
+func TestHTTPCertPort(t *testing.T) {
+	configStore[configKeyRemoteAllowedHosts] = []interface{}{"127.0.0.1"}
+	defer delete(configStore, configKeyRemoteAllowedHosts)
+
+	_, _, _, err := httpServiceHandler{}.handleCert(context.Background(), "127.0.0.1:22")
+	assert.EqualError(t, err, "only port 443 is supported")
+}
+
+func TestCertExpiryBadge(t *testing.T) {
+	day := 24 * time.Hour
+
+	cases := map[time.Duration][2]string{
+		-time.Hour:           {"expired", colorNameRed},
+		0:                    {"expired", colorNameRed},
+		day + time.Hour:      {"1 day", colorNameRed},
+		6 * day:              {"6 days", colorNameRed},
+		10 * day:             {"10 days", colorNameOrange},
+		20 * day:             {"20 days", colorNameYellow},
+		90*day + time.Minute: {"90 days", colorNameBrightGreen},
+	}
+
+	for in, expect := range cases {
+		text, color := certExpiryBadge(in)
+		assert.Equal(t, expect[0], text, in.String())
+		assert.Equal(t, expect[1], color, in.String())
+	}
+}
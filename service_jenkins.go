@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const jenkinsCacheDuration = 5 * time.Minute
+
+const (
+	// #configStore jenkins.<host>.url - string - Base URL of the Jenkins instance addressed as <host> (host is rejected if unset)
+	configKeyJenkinsURL = "jenkins.%s.url"
+	// #configStore jenkins.<host>.username - string - Username for API token auth against <host>
+	configKeyJenkinsUsername = "jenkins.%s.username"
+	// #configStore jenkins.<host>.token - string - API token of jenkins.<host>.username
+	configKeyJenkinsToken = "jenkins.%s.token"
+)
+
+func init() {
+	registerServiceHandler("jenkins", jenkinsServiceHandler{})
+}
+
+type jenkinsServiceHandler struct{}
+
+func (jenkinsServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "Jenkins job status",
+			DemoPath:    "/jenkins/ci.example.com/badge-gen",
+			Arguments:   []string{"<host>", "<job>", "[sub-job / branch...]"},
+		},
+	}
+}
+
+func (jenkinsServiceHandler) IsEnabled() bool { return true }
+
+func (jenkinsServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide host and job")
+		return title, text, color, err
+	}
+
+	base := configStore.Str(fmt.Sprintf(configKeyJenkinsURL, params[0]))
+	if base == "" {
+		err = fmt.Errorf("jenkins %q is not configured", params[0])
+		return title, text, color, err
+	}
+
+	// Folders and multibranch pipelines are nested jobs, the branch
+	// is just another job level
+	jobPath := make([]string, 0, 2*len(params[1:])) //nolint:gomnd
+	for _, job := range params[1:] {
+		jobPath = append(jobPath, "job", url.PathEscape(job))
+	}
+	path := strings.Join(append(jobPath, "lastBuild", "api", "json"), "/")
+	cacheKey := params[0] + "/" + path
+
+	text, err = cacheStore.Get("jenkins", cacheKey)
+
+	if err != nil {
+		req, _ := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(base, "/")+"/"+path+"?tree=building,result", nil)
+		if token := configStore.Str(fmt.Sprintf(configKeyJenkinsToken, params[0])); token != "" {
+			req.SetBasicAuth(configStore.Str(fmt.Sprintf(configKeyJenkinsUsername, params[0])), token)
+		}
+
+		r := struct {
+			Building bool    `json:"building"`
+			Result   *string `json:"result"`
+		}{}
+
+		if err = fetchJSON(req, &r); err != nil {
+			return title, text, color, err
+		}
+
+		text = ciStatusUnknown
+		switch {
+		case r.Building:
+			text = "running"
+		case r.Result != nil:
+			if s, ok := map[string]string{
+				"SUCCESS":   "passed",
+				"UNSTABLE":  "unstable",
+				"FAILURE":   "failed",
+				"ABORTED":   "canceled",
+				"NOT_BUILT": "skipped",
+			}[*r.Result]; ok {
+				text = s
+			}
+		}
+
+		logErr(cacheStore.Set("jenkins", cacheKey, text, jenkinsCacheDuration), "writing Jenkins status to cache")
+	}
+
+	return "build", text, ciStatusColor(text), nil
+}
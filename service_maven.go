@@ -0,0 +1,180 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	mavenCacheDuration     = 30 * time.Minute
+	mavenDefaultRepository = "https://repo1.maven.org/maven2"
+)
+
+// #configStore maven.repository - string - Base URL of the Maven repository (defaults to https://repo1.maven.org/maven2)
+const configKeyMavenRepository = "maven.repository"
+
+// mavenPrerelease matches the qualifiers of Maven prereleases like
+// 1.0-M1, 2.0.0.RC1, 1.0-beta-2 or 1.0-SNAPSHOT
+var mavenPrerelease = regexp.MustCompile(`(?i)(?:^|[.\-\d])(?:a|alpha|b|beta|m|milestone|rc|cr|ea|preview|snapshot)(?:[.\-]?\d+)*$`)
+
+func init() {
+	registerServiceHandler("maven", mavenServiceHandler{})
+}
+
+type mavenServiceHandler struct{}
+
+type mavenPOM struct {
+	Licenses []struct {
+		Name string `xml:"name"`
+	} `xml:"licenses>license"`
+	Properties struct {
+		CompilerRelease string `xml:"maven.compiler.release"`
+		CompilerTarget  string `xml:"maven.compiler.target"`
+		CompilerSource  string `xml:"maven.compiler.source"`
+	} `xml:"properties"`
+}
+
+func (mavenServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "Maven Central version",
+			DemoPath:    "/maven/version/org.apache.commons/commons-lang3",
+			Arguments:   []string{"version", "<group>", "<artifact>", "[prerelease]"},
+		},
+		{
+			ServiceName: "Maven Central license",
+			DemoPath:    "/maven/license/org.apache.commons/commons-lang3",
+			Arguments:   []string{"license", "<group>", "<artifact>"},
+		},
+		{
+			ServiceName: "Maven Central Java version",
+			DemoPath:    "/maven/runtime/org.apache.commons/commons-lang3",
+			Arguments:   []string{"runtime", "<group>", "<artifact>"},
+		},
+	}
+}
+
+func (mavenServiceHandler) IsEnabled() bool { return true }
+
+func (m mavenServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 3 { //nolint:gomnd
+		err = errors.New("you need to provide command, group and artifact")
+		return title, text, color, err
+	}
+
+	var fetch func(context.Context, string, []string) (string, error)
+	switch params[0] {
+	case "version":
+		title, fetch = "maven", m.fetchVersion
+	case "downloads":
+		err = errors.New("maven repositories do not publish download counts")
+		return title, text, color, err
+	case "license":
+		title, fetch = "license", m.fetchLicense
+	case "runtime":
+		title, fetch = "java", m.fetchRuntime
+	default:
+		err = errors.New("an unknown service command was called")
+		return title, text, color, err
+	}
+
+	cacheKey := strings.Join(params, "/")
+	text, err = cacheStore.Get("maven", cacheKey)
+
+	if err != nil {
+		artifactPath := strings.ReplaceAll(params[1], ".", "/") + "/" + params[2]
+		if text, err = fetch(ctx, artifactPath, params[3:]); err != nil {
+			return title, text, color, err
+		}
+
+		logErr(cacheStore.Set("maven", cacheKey, text, mavenCacheDuration), "writing Maven result to cache")
+	}
+
+	return title, text, registryBadgeColor(params[0], text), nil
+}
+
+func (m mavenServiceHandler) fetchVersion(ctx context.Context, artifactPath string, opts []string) (string, error) {
+	return m.fetchLatestVersion(ctx, artifactPath, len(opts) > 0 && opts[0] == "prerelease")
+}
+
+func (m mavenServiceHandler) fetchLicense(ctx context.Context, artifactPath string, _ []string) (string, error) {
+	pom, err := m.fetchLatestPOM(ctx, artifactPath)
+	if err != nil {
+		return "", err
+	}
+
+	var licenses []string
+	for _, l := range pom.Licenses {
+		if l.Name != "" {
+			licenses = append(licenses, l.Name)
+		}
+	}
+
+	if len(licenses) == 0 {
+		return "None", nil
+	}
+
+	return strings.Join(licenses, ", "), nil
+}
+
+func (m mavenServiceHandler) fetchRuntime(ctx context.Context, artifactPath string, _ []string) (string, error) {
+	pom, err := m.fetchLatestPOM(ctx, artifactPath)
+	if err != nil {
+		return "", err
+	}
+
+	for _, v := range []string{pom.Properties.CompilerRelease, pom.Properties.CompilerTarget, pom.Properties.CompilerSource} {
+		if v != "" {
+			return ">= " + v, nil
+		}
+	}
+
+	return "unknown", nil
+}
+
+func (m mavenServiceHandler) fetchLatestPOM(ctx context.Context, artifactPath string) (*mavenPOM, error) {
+	v, err := m.fetchLatestVersion(ctx, artifactPath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	artifact := artifactPath[strings.LastIndex(artifactPath, "/")+1:]
+	base := strings.TrimRight(configStore.StrDefault(configKeyMavenRepository, mavenDefaultRepository), "/")
+
+	pom := &mavenPOM{}
+	req, _ := http.NewRequestWithContext(ctx, "GET", strings.Join([]string{base, artifactPath, v, artifact + "-" + v + ".pom"}, "/"), nil)
+	if err = fetchXML(req, pom); err != nil {
+		return nil, errors.Wrap(err, "fetching POM")
+	}
+
+	return pom, nil
+}
+
+func (mavenServiceHandler) fetchLatestVersion(ctx context.Context, artifactPath string, prerelease bool) (string, error) {
+	base := strings.TrimRight(configStore.StrDefault(configKeyMavenRepository, mavenDefaultRepository), "/")
+
+	meta := struct {
+		Versions []string `xml:"versioning>versions>version"`
+	}{}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", strings.Join([]string{base, artifactPath, "maven-metadata.xml"}, "/"), nil)
+	if err := fetchXML(req, &meta); err != nil {
+		return "", errors.Wrap(err, "fetching metadata")
+	}
+
+	// The release field of the metadata contains the last deployed
+	// version which might be a prerelease or a backport, so the
+	// versions are compared instead. Maven versions are not semver
+	// (31.1-jre) and are compared loosely.
+	v := latestLooseVersion(meta.Versions, prerelease, mavenPrerelease.MatchString)
+	if v == "" {
+		return "", errors.New("artifact has no releases")
+	}
+
+	return v, nil
+}
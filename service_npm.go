@@ -0,0 +1,197 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	npmCacheDuration    = 30 * time.Minute
+	npmDefaultRegistry  = "https://registry.npmjs.org"
+	npmDefaultDownloads = "https://api.npmjs.org"
+)
+
+const (
+	// #configStore npm.registry - string - Base URL of the npm registry (defaults to https://registry.npmjs.org)
+	configKeyNPMRegistry = "npm.registry"
+	// #configStore npm.downloads_api - string - Base URL of the npm downloads API (defaults to https://api.npmjs.org)
+	configKeyNPMDownloads = "npm.downloads_api"
+)
+
+func init() {
+	registerServiceHandler("npm", npmServiceHandler{})
+}
+
+type npmServiceHandler struct{}
+
+type npmPackage struct {
+	DistTags map[string]string `json:"dist-tags"`
+	Versions map[string]struct {
+		License interface{}       `json:"license"`
+		Engines map[string]string `json:"engines"`
+	} `json:"versions"`
+}
+
+func (npmServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "npm version",
+			DemoPath:    "/npm/version/@babel/core",
+			Arguments:   []string{"version", "<package>", "[prerelease]"},
+		},
+		{
+			ServiceName: "npm downloads",
+			DemoPath:    "/npm/downloads/express/weekly",
+			Arguments:   []string{"downloads", "<package>", "[daily|weekly|monthly|yearly]"},
+		},
+		{
+			ServiceName: "npm license",
+			DemoPath:    "/npm/license/express",
+			Arguments:   []string{"license", "<package>"},
+		},
+		{
+			ServiceName: "npm node engine",
+			DemoPath:    "/npm/runtime/express",
+			Arguments:   []string{"runtime", "<package>"},
+		},
+	}
+}
+
+func (npmServiceHandler) IsEnabled() bool { return true }
+
+func (n npmServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide command and package")
+		return title, text, color, err
+	}
+
+	command, pkg, opts := params[0], params[1], params[2:]
+	if strings.HasPrefix(pkg, "@") && !strings.Contains(pkg, "/") && len(opts) > 0 {
+		// Scoped package given without escaping the slash
+		pkg, opts = pkg+"/"+opts[0], opts[1:]
+	}
+
+	var fetch func(context.Context, string, []string) (string, error)
+	switch command {
+	case "version":
+		title, fetch = "npm", n.fetchVersion
+	case "downloads":
+		title, fetch = "downloads", n.fetchDownloads
+	case "license":
+		title, fetch = "license", n.fetchLicense
+	case "runtime":
+		title, fetch = "node", n.fetchRuntime
+	default:
+		err = errors.New("an unknown service command was called")
+		return title, text, color, err
+	}
+
+	cacheKey := strings.Join(append([]string{command, pkg}, opts...), "/")
+	text, err = cacheStore.Get("npm", cacheKey)
+
+	if err != nil {
+		if text, err = fetch(ctx, pkg, opts); err != nil {
+			return title, text, color, err
+		}
+
+		logErr(cacheStore.Set("npm", cacheKey, text, npmCacheDuration), "writing npm result to cache")
+	}
+
+	return title, text, registryBadgeColor(command, text), nil
+}
+
+func (n npmServiceHandler) fetchVersion(ctx context.Context, pkg string, opts []string) (string, error) {
+	p, err := n.fetchPackage(ctx, pkg)
+	if err != nil {
+		return "", err
+	}
+
+	if len(opts) > 0 && opts[0] == "prerelease" {
+		return latestVersion(sortedKeys(p.Versions), true), nil
+	}
+
+	return p.DistTags["latest"], nil
+}
+
+func (npmServiceHandler) fetchDownloads(ctx context.Context, pkg string, opts []string) (string, error) {
+	period := "weekly"
+	if len(opts) > 0 {
+		period = opts[0]
+	}
+
+	point, ok := map[string]string{
+		"daily":   "last-day",
+		"weekly":  "last-week",
+		"monthly": "last-month",
+		"yearly":  "last-year",
+	}[period]
+	if !ok {
+		return "", errors.Errorf("unsupported period %q", period)
+	}
+
+	base := strings.TrimRight(configStore.StrDefault(configKeyNPMDownloads, npmDefaultDownloads), "/")
+
+	r := struct {
+		Downloads int64 `json:"downloads"`
+	}{}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", strings.Join([]string{base, "downloads", "point", point, pkg}, "/"), nil)
+	if err := fetchJSON(req, &r); err != nil {
+		return "", err
+	}
+
+	return metricFormat(r.Downloads) + registryPeriodSuffix(period), nil
+}
+
+func (n npmServiceHandler) fetchLicense(ctx context.Context, pkg string, _ []string) (string, error) {
+	p, err := n.fetchPackage(ctx, pkg)
+	if err != nil {
+		return "", err
+	}
+
+	// License used to be an object before SPDX expressions were introduced
+	switch l := p.Versions[p.DistTags["latest"]].License.(type) {
+	case string:
+		return l, nil
+	case map[string]interface{}:
+		if t, ok := l["type"].(string); ok {
+			return t, nil
+		}
+	}
+
+	return "None", nil
+}
+
+func (n npmServiceHandler) fetchRuntime(ctx context.Context, pkg string, _ []string) (string, error) {
+	p, err := n.fetchPackage(ctx, pkg)
+	if err != nil {
+		return "", err
+	}
+
+	if node := p.Versions[p.DistTags["latest"]].Engines["node"]; node != "" {
+		return node, nil
+	}
+
+	return "any", nil
+}
+
+func (npmServiceHandler) fetchPackage(ctx context.Context, pkg string) (*npmPackage, error) {
+	base := strings.TrimRight(configStore.StrDefault(configKeyNPMRegistry, npmDefaultRegistry), "/")
+
+	p := &npmPackage{}
+	req, _ := http.NewRequestWithContext(ctx, "GET", base+"/"+url.PathEscape(pkg), nil)
+	if err := fetchJSON(req, p); err != nil {
+		return nil, errors.Wrap(err, "fetching package")
+	}
+
+	if len(p.Versions) == 0 {
+		return nil, errors.New("package has no versions")
+	}
+
+	return p, nil
+}
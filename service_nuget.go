@@ -0,0 +1,220 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	nugetCacheDuration         = 30 * time.Minute
+	nugetDefaultFlatContainer  = "https://api.nuget.org/v3-flatcontainer"
+	nugetDefaultSearchEndpoint = "https://azuresearch-usnc.nuget.org"
+)
+
+const (
+	// #configStore nuget.flatcontainer_url - string - Base URL of the NuGet package content resource (defaults to https://api.nuget.org/v3-flatcontainer)
+	configKeyNuGetFlatContainer = "nuget.flatcontainer_url"
+	// #configStore nuget.search_url - string - Base URL of the NuGet search service (defaults to https://azuresearch-usnc.nuget.org)
+	configKeyNuGetSearch = "nuget.search_url"
+)
+
+func init() {
+	registerServiceHandler("nuget", nugetServiceHandler{})
+}
+
+type nugetServiceHandler struct{}
+
+type nugetNuspec struct {
+	Metadata struct {
+		License struct {
+			Type  string `xml:"type,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"license"`
+		Dependencies struct {
+			Groups []struct {
+				TargetFramework string `xml:"targetFramework,attr"`
+			} `xml:"group"`
+		} `xml:"dependencies"`
+	} `xml:"metadata"`
+}
+
+func (nugetServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "NuGet version",
+			DemoPath:    "/nuget/version/Newtonsoft.Json",
+			Arguments:   []string{"version", "<package>", "[prerelease]"},
+		},
+		{
+			ServiceName: "NuGet downloads",
+			DemoPath:    "/nuget/downloads/Newtonsoft.Json",
+			Arguments:   []string{"downloads", "<package>"},
+		},
+		{
+			ServiceName: "NuGet license",
+			DemoPath:    "/nuget/license/Newtonsoft.Json",
+			Arguments:   []string{"license", "<package>"},
+		},
+		{
+			ServiceName: "NuGet target frameworks",
+			DemoPath:    "/nuget/runtime/Newtonsoft.Json",
+			Arguments:   []string{"runtime", "<package>"},
+		},
+	}
+}
+
+func (nugetServiceHandler) IsEnabled() bool { return true }
+
+func (n nugetServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide command and package")
+		return title, text, color, err
+	}
+
+	var fetch func(context.Context, string, []string) (string, error)
+	switch params[0] {
+	case "version":
+		title, fetch = "nuget", n.fetchVersion
+	case "downloads":
+		title, fetch = "downloads", n.fetchDownloads
+	case "license":
+		title, fetch = "license", n.fetchLicense
+	case "runtime":
+		title, fetch = "frameworks", n.fetchRuntime
+	default:
+		err = errors.New("an unknown service command was called")
+		return title, text, color, err
+	}
+
+	cacheKey := strings.Join(params, "/")
+	text, err = cacheStore.Get("nuget", cacheKey)
+
+	if err != nil {
+		// The package content resource only knows lowercased IDs
+		if text, err = fetch(ctx, strings.ToLower(params[1]), params[2:]); err != nil {
+			return title, text, color, err
+		}
+
+		logErr(cacheStore.Set("nuget", cacheKey, text, nugetCacheDuration), "writing NuGet result to cache")
+	}
+
+	return title, text, registryBadgeColor(params[0], text), nil
+}
+
+func (n nugetServiceHandler) fetchVersion(ctx context.Context, pkg string, opts []string) (string, error) {
+	return n.fetchLatestVersion(ctx, pkg, len(opts) > 0 && opts[0] == "prerelease")
+}
+
+func (nugetServiceHandler) fetchDownloads(ctx context.Context, pkg string, _ []string) (string, error) {
+	base := strings.TrimRight(configStore.StrDefault(configKeyNuGetSearch, nugetDefaultSearchEndpoint), "/")
+
+	r := struct {
+		Data []struct {
+			TotalDownloads int64 `json:"totalDownloads"`
+		} `json:"data"`
+	}{}
+
+	query := url.Values{
+		"q":           []string{"packageid:" + pkg},
+		"prerelease":  []string{"true"},
+		"semVerLevel": []string{"2.0.0"},
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", base+"/query?"+query.Encode(), nil)
+	if err := fetchJSON(req, &r); err != nil {
+		return "", errors.Wrap(err, "searching package")
+	}
+
+	if len(r.Data) == 0 {
+		return "", errors.New("package not found")
+	}
+
+	return metricFormat(r.Data[0].TotalDownloads), nil
+}
+
+func (n nugetServiceHandler) fetchLicense(ctx context.Context, pkg string, _ []string) (string, error) {
+	spec, err := n.fetchLatestNuspec(ctx, pkg)
+	if err != nil {
+		return "", err
+	}
+
+	// License files can not be displayed in a badge
+	if spec.Metadata.License.Type != "expression" || spec.Metadata.License.Value == "" {
+		return "None", nil
+	}
+
+	return spec.Metadata.License.Value, nil
+}
+
+func (n nugetServiceHandler) fetchRuntime(ctx context.Context, pkg string, _ []string) (string, error) {
+	spec, err := n.fetchLatestNuspec(ctx, pkg)
+	if err != nil {
+		return "", err
+	}
+
+	var frameworks []string
+	for _, g := range spec.Metadata.Dependencies.Groups {
+		if g.TargetFramework != "" {
+			frameworks = append(frameworks, g.TargetFramework)
+		}
+	}
+
+	if len(frameworks) == 0 {
+		return "any", nil
+	}
+
+	return strings.Join(frameworks, " | "), nil
+}
+
+func (n nugetServiceHandler) fetchLatestNuspec(ctx context.Context, pkg string) (*nugetNuspec, error) {
+	v, err := n.fetchLatestVersion(ctx, pkg, false)
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimRight(configStore.StrDefault(configKeyNuGetFlatContainer, nugetDefaultFlatContainer), "/")
+
+	spec := &nugetNuspec{}
+	req, _ := http.NewRequestWithContext(ctx, "GET", strings.Join([]string{base, pkg, strings.ToLower(v), pkg + ".nuspec"}, "/"), nil)
+	if err = fetchXML(req, spec); err != nil {
+		return nil, errors.Wrap(err, "fetching nuspec")
+	}
+
+	return spec, nil
+}
+
+func (nugetServiceHandler) fetchLatestVersion(ctx context.Context, pkg string, prerelease bool) (string, error) {
+	base := strings.TrimRight(configStore.StrDefault(configKeyNuGetFlatContainer, nugetDefaultFlatContainer), "/")
+
+	r := struct {
+		Versions []string `json:"versions"`
+	}{}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", strings.Join([]string{base, pkg, "index.json"}, "/"), nil)
+	if err := fetchJSON(req, &r); err != nil {
+		return "", errors.Wrap(err, "fetching versions")
+	}
+
+	if len(r.Versions) == 0 {
+		return "", errors.New("package has no versions")
+	}
+
+	// Legacy four-part versions are no valid semver so all versions
+	// are compared loosely to order them among the semver ones
+	if v := latestLooseVersion(r.Versions, prerelease, nugetPrerelease); v != "" {
+		return v, nil
+	}
+
+	return "", errors.New("package has no stable versions")
+}
+
+// nugetPrerelease reports whether the version has a prerelease suffix
+// which NuGet separates by a dash
+func nugetPrerelease(v string) bool {
+	return strings.Contains(v, "-")
+}
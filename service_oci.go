@@ -0,0 +1,390 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Luzifer/go_helpers/v2/str"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	ociCacheDuration = 30 * time.Minute
+	// ociMaxTagPages limits the pages of tags fetched from registries
+	// paginating the tag list
+	ociMaxTagPages = 50
+)
+
+const (
+	// #configStore oci.registries - []string - Registry hosts allowed to be queried (defaults to ghcr.io, quay.io, registry.gitlab.com, docker.io, gcr.io, public.ecr.aws)
+	configKeyOCIRegistries = "oci.registries"
+	// #configStore oci.<registry>.username - string - Username to authenticate against <registry> (required for private images)
+	configKeyOCIUsername = "oci.%s.username"
+	// #configStore oci.<registry>.password - string - Password / token to authenticate against <registry> (required for private images)
+	configKeyOCIPassword = "oci.%s.password"
+	// #configStore oci.<registry>.auth_hosts - []string - Additional hosts of token services the credentials of <registry> are sent to (the registry itself, auth.docker.io for docker.io and gitlab.com for registry.gitlab.com are always allowed)
+	configKeyOCIAuthHosts = "oci.%s.auth_hosts"
+)
+
+var (
+	ociDefaultRegistries = []string{"ghcr.io", "quay.io", "registry.gitlab.com", "docker.io", "gcr.io", "public.ecr.aws"}
+	ociChallengeParam    = regexp.MustCompile(`(\w+)="([^"]*)"`)
+	ociNextLink          = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+
+	// ociDefaultAuthHosts contains the token services of the default
+	// registries not hosted on the registry host itself
+	ociDefaultAuthHosts = map[string][]string{
+		"docker.io":           {"auth.docker.io"},
+		"registry.gitlab.com": {"gitlab.com"},
+	}
+
+	ociManifestTypes = []string{
+		"application/vnd.oci.image.index.v1+json",
+		"application/vnd.docker.distribution.manifest.list.v2+json",
+		"application/vnd.oci.image.manifest.v1+json",
+		"application/vnd.docker.distribution.manifest.v2+json",
+	}
+)
+
+func init() {
+	registerServiceHandler("oci", ociServiceHandler{})
+}
+
+type ociPlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant"`
+}
+
+type ociManifest struct {
+	// Image index / manifest list
+	Manifests []struct {
+		Digest   string       `json:"digest"`
+		Platform *ociPlatform `json:"platform"`
+	} `json:"manifests"`
+
+	// Image manifest
+	Config struct {
+		Size int64 `json:"size"`
+	} `json:"config"`
+	Layers []struct {
+		Size int64 `json:"size"`
+	} `json:"layers"`
+}
+
+type ociServiceHandler struct{}
+
+func (ociServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "OCI image latest tag",
+			DemoPath:    "/oci/latest-tag/quay.io/prometheus/prometheus",
+			Arguments:   []string{"latest-tag", "<registry>", "<repository...>"},
+		},
+		{
+			ServiceName: "OCI image size",
+			DemoPath:    "/oci/size/ghcr.io/luzifer/badge-gen:latest",
+			Arguments:   []string{"size[:architecture]", "<registry>", "<repository...>[:tag]"},
+		},
+		{
+			ServiceName: "OCI image architectures",
+			DemoPath:    "/oci/architectures/quay.io/prometheus/prometheus:latest",
+			Arguments:   []string{"architectures", "<registry>", "<repository...>[:tag]"},
+		},
+	}
+}
+
+func (ociServiceHandler) IsEnabled() bool { return true }
+
+func (o ociServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 3 { //nolint:gomnd
+		err = errors.New("you need to provide command, registry and repository")
+		return title, text, color, err
+	}
+
+	registries := configStore.StrSlice(configKeyOCIRegistries)
+	if registries == nil {
+		registries = ociDefaultRegistries
+	}
+
+	if !str.StringInSlice(params[1], registries) {
+		err = fmt.Errorf("registry %q is not allowed", params[1])
+		return title, text, color, err
+	}
+
+	repo, tag := strings.Join(params[2:], "/"), "latest"
+	if idx := strings.LastIndex(repo, ":"); idx > 0 {
+		repo, tag = repo[:idx], repo[idx+1:]
+	}
+
+	command, arch, _ := strings.Cut(params[0], ":")
+
+	switch command {
+	case "latest-tag":
+		title, text, color, err = o.handleLatestTag(ctx, params[1], repo)
+	case "size":
+		title, text, color, err = o.handleSize(ctx, params[1], repo, tag, arch)
+	case "architectures":
+		title, text, color, err = o.handleArchitectures(ctx, params[1], repo, tag)
+	default:
+		err = errors.New("an unknown service command was called")
+	}
+
+	return title, text, color, err
+}
+
+func (o ociServiceHandler) handleLatestTag(ctx context.Context, registry, repo string) (title, text, color string, err error) {
+	cacheKey := registry + "/" + repo
+
+	text, err = cacheStore.Get("oci_latest_tag", cacheKey)
+
+	if err != nil {
+		var tags []string
+		if tags, err = o.fetchTags(ctx, registry, repo); err != nil {
+			return title, text, color, err
+		}
+
+		switch {
+		case latestVersion(tags, false) != "":
+			text = latestVersion(tags, false)
+		case str.StringInSlice("latest", tags):
+			text = "latest"
+		default:
+			text = "None"
+		}
+
+		logErr(cacheStore.Set("oci_latest_tag", cacheKey, text, ociCacheDuration), "writing OCI latest tag to cache")
+	}
+
+	return "tag", text, versionColor(text), nil
+}
+
+func (o ociServiceHandler) handleSize(ctx context.Context, registry, repo, tag, arch string) (title, text, color string, err error) {
+	if arch == "" {
+		arch = "amd64"
+	}
+	cacheKey := strings.Join([]string{registry, repo, tag, arch}, "/")
+
+	text, err = cacheStore.Get("oci_size", cacheKey)
+
+	if err != nil {
+		var m ociManifest
+		if err = o.fetchRegistry(ctx, registry, repo, "manifests/"+tag, ociManifestTypes, &m); err != nil {
+			return title, text, color, err
+		}
+
+		if len(m.Manifests) > 0 {
+			digest := ""
+			for _, sub := range m.Manifests {
+				if sub.Platform != nil && (arch == sub.Platform.Architecture || arch == sub.Platform.Architecture+"/"+sub.Platform.Variant) {
+					digest = sub.Digest
+					break
+				}
+			}
+
+			if digest == "" {
+				return title, text, color, errors.Errorf("architecture %q not found", arch)
+			}
+
+			m = ociManifest{}
+			if err = o.fetchRegistry(ctx, registry, repo, "manifests/"+digest, ociManifestTypes, &m); err != nil {
+				return title, text, color, err
+			}
+		}
+
+		size := m.Config.Size
+		for _, l := range m.Layers {
+			size += l.Size
+		}
+
+		text = byteFormat(size)
+		logErr(cacheStore.Set("oci_size", cacheKey, text, ociCacheDuration), "writing OCI image size to cache")
+	}
+
+	return "image size", text, colorNameBlue, nil
+}
+
+func (o ociServiceHandler) handleArchitectures(ctx context.Context, registry, repo, tag string) (title, text, color string, err error) {
+	cacheKey := strings.Join([]string{registry, repo, tag}, "/")
+
+	text, err = cacheStore.Get("oci_architectures", cacheKey)
+
+	if err != nil {
+		var m ociManifest
+		if err = o.fetchRegistry(ctx, registry, repo, "manifests/"+tag, ociManifestTypes, &m); err != nil {
+			return title, text, color, err
+		}
+
+		count := 1 // Single-arch image manifest
+		if len(m.Manifests) > 0 {
+			count = 0
+			for _, sub := range m.Manifests {
+				// Attestation manifests are attached as unknown/unknown
+				if sub.Platform == nil || sub.Platform.Architecture == "unknown" {
+					continue
+				}
+				count++
+			}
+		}
+
+		text = strconv.Itoa(count)
+		logErr(cacheStore.Set("oci_architectures", cacheKey, text, ociCacheDuration), "writing OCI architectures to cache")
+	}
+
+	return "architectures", text, colorNameBlue, nil
+}
+
+// fetchTags lists the tags of the repository following the pagination
+// links of registries returning the tags in pages
+func (o ociServiceHandler) fetchTags(ctx context.Context, registry, repo string) ([]string, error) {
+	reqURL, err := url.Parse(o.registryURL(registry, repo, "tags/list"))
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing registry URL")
+	}
+
+	var tags []string
+	for i := 0; i < ociMaxTagPages; i++ {
+		r := struct {
+			Tags []string `json:"tags"`
+		}{}
+
+		header, err := o.fetchRegistryURL(ctx, registry, reqURL.String(), nil, &r)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, r.Tags...)
+
+		m := ociNextLink.FindStringSubmatch(header.Get("Link"))
+		if m == nil {
+			return tags, nil
+		}
+
+		// The token for the registry must not be sent elsewhere
+		next, err := reqURL.Parse(m[1])
+		if err != nil || next.Host != reqURL.Host {
+			return nil, errors.Errorf("invalid pagination link %q", m[1])
+		}
+		reqURL = next
+	}
+
+	return nil, errors.Errorf("repository has more than %d pages of tags", ociMaxTagPages)
+}
+
+// fetchRegistry executes a request against the distribution API of
+// the registry and answers bearer token challenges if the registry
+// requires them (which most do even for anonymous pulls)
+func (o ociServiceHandler) fetchRegistry(ctx context.Context, registry, repo, path string, accept []string, out interface{}) error {
+	_, err := o.fetchRegistryURL(ctx, registry, o.registryURL(registry, repo, path), accept, out)
+	return err
+}
+
+func (o ociServiceHandler) fetchRegistryURL(ctx context.Context, registry, reqURL string, accept []string, out interface{}) (http.Header, error) {
+	header, err := fetchJSONHeader(o.newRequest(ctx, reqURL, accept, ""), out)
+
+	var statusErr fetchStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		return header, err
+	}
+
+	token, err := o.fetchToken(ctx, registry, statusErr.Header.Get("WWW-Authenticate"))
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching registry token")
+	}
+
+	return fetchJSONHeader(o.newRequest(ctx, reqURL, accept, "Bearer "+token), out)
+}
+
+// fetchToken requests a token from the service named in the challenge,
+// the configured credentials are only sent to the allowed auth hosts
+func (o ociServiceHandler) fetchToken(ctx context.Context, registry, challenge string) (string, error) {
+	scheme, rawParams, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "bearer") {
+		return "", errors.Errorf("unsupported auth scheme %q", scheme)
+	}
+
+	params := map[string]string{}
+	for _, m := range ociChallengeParam.FindAllStringSubmatch(rawParams, -1) {
+		params[m[1]] = m[2]
+	}
+
+	if params["realm"] == "" {
+		return "", errors.New("challenge contains no realm")
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme != "https" || realm.Host == "" {
+		return "", errors.Errorf("challenge realm %q is no HTTPS URL", params["realm"])
+	}
+
+	query := realm.Query()
+	for _, k := range []string{"service", "scope"} {
+		if params[k] != "" {
+			query.Set(k, params[k])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", realm.String(), nil)
+	if pass := configStore.Str(fmt.Sprintf(configKeyOCIPassword, registry)); pass != "" && o.authHostAllowed(registry, realm.Host) {
+		req.SetBasicAuth(configStore.Str(fmt.Sprintf(configKeyOCIUsername, registry)), pass)
+	}
+
+	r := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := fetchJSON(req, &r); err != nil {
+		return "", err
+	}
+
+	if r.Token != "" {
+		return r.Token, nil
+	}
+	return r.AccessToken, nil
+}
+
+// authHostAllowed reports whether the credentials of the registry may
+// be sent to the token service on the given host
+func (o ociServiceHandler) authHostAllowed(registry, host string) bool {
+	if host == registry || host == o.registryHost(registry) {
+		return true
+	}
+
+	return str.StringInSlice(host, ociDefaultAuthHosts[registry]) ||
+		str.StringInSlice(host, configStore.StrSlice(fmt.Sprintf(configKeyOCIAuthHosts, registry)))
+}
+
+// registryHost returns the host serving the distribution API of the
+// registry
+func (ociServiceHandler) registryHost(registry string) string {
+	if registry == "docker.io" {
+		return "registry-1.docker.io"
+	}
+	return registry
+}
+
+func (o ociServiceHandler) registryURL(registry, repo, path string) string {
+	if registry == "docker.io" && !strings.Contains(repo, "/") {
+		repo = "library/" + repo
+	}
+
+	return strings.Join([]string{"https:/", o.registryHost(registry), "v2", repo, path}, "/")
+}
+
+func (ociServiceHandler) newRequest(ctx context.Context, reqURL string, accept []string, auth string) *http.Request {
+	req, _ := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	return req
+}
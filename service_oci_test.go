@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestOCILatestTagPagination(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/luzifer/badge-gen/tags/list" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.URL.Query().Get("last") == "" {
+			w.Header().Set("Link", `</v2/luzifer/badge-gen/tags/list?last=v1.1.0&n=2>; rel="next"`)
+			fmt.Fprint(w, `{"tags":["v1.0.0","v1.1.0"]}`)
+			return
+		}
+		fmt.Fprint(w, `{"tags":["v2.0.0","latest"]}`)
+	}))
+	defer srv.Close()
+
+	transport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = srv.Client().Transport
+	defer func() { http.DefaultClient.Transport = transport }()
+
+	registry := strings.TrimPrefix(srv.URL, "https://")
+	configStore[configKeyOCIRegistries] = []interface{}{registry}
+	defer delete(configStore, configKeyOCIRegistries)
+
+	_, text, _, err := ociServiceHandler{}.Handle(context.Background(), []string{"latest-tag", registry, "luzifer", "badge-gen"})
+	assert.NoError(t, err)
+	assert.Equal(t, "v2.0.0", text)
+}
+
+func TestOCIAuthHostAllowed(t *testing.T) {
+	configStore["oci.quay.io.auth_hosts"] = []interface{}{"auth.example.com"}
+	defer delete(configStore, "oci.quay.io.auth_hosts")
+
+	cases := map[[2]string]bool{
+		{"ghcr.io", "ghcr.io"}:                    true,
+		{"docker.io", "auth.docker.io"}:           true,
+		{"docker.io", "registry-1.docker.io"}:     true,
+		{"registry.gitlab.com", "gitlab.com"}:     true,
+		{"quay.io", "auth.example.com"}:           true,
+		{"ghcr.io", "auth.example.com"}:           false,
+		{"docker.io", "evil.example.com"}:         false,
+		{"registry.gitlab.com", "auth.docker.io"}: false,
+	}
+
+	for in, expect := range cases {
+		assert.Equal(t, expect, ociServiceHandler{}.authHostAllowed(in[0], in[1]), "%s -> %s", in[0], in[1])
+	}
+}
+
+func TestOCITokenRealm(t *testing.T) {
+	var auth string
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		fmt.Fprint(w, `{"token":"t0k3n"}`)
+	}))
+	defer srv.Close()
+
+	transport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = srv.Client().Transport
+	defer func() { http.DefaultClient.Transport = transport }()
+
+	configStore["oci.ghcr.io.username"] = "luzifer"
+	configStore["oci.ghcr.io.password"] = "s3cr3t"
+	defer delete(configStore, "oci.ghcr.io.username")
+	defer delete(configStore, "oci.ghcr.io.password")
+
+	// Credentials must not be sent to a realm not belonging to the registry
+	token, err := ociServiceHandler{}.fetchToken(context.Background(), "ghcr.io", `Bearer realm="`+srv.URL+`/token",service="ghcr.io"`)
+	assert.NoError(t, err)
+	assert.Equal(t, "t0k3n", token)
+	assert.Empty(t, auth)
+
+	configStore["oci.ghcr.io.auth_hosts"] = []interface{}{strings.TrimPrefix(srv.URL, "https://")}
+	defer delete(configStore, "oci.ghcr.io.auth_hosts")
+
+	_, err = ociServiceHandler{}.fetchToken(context.Background(), "ghcr.io", `Bearer realm="`+srv.URL+`/token",service="ghcr.io"`)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, auth)
+
+	_, err = ociServiceHandler{}.fetchToken(context.Background(), "ghcr.io", `Bearer realm="http://ghcr.io/token",service="ghcr.io"`)
+	assert.EqualError(t, err, `challenge realm "http://ghcr.io/token" is no HTTPS URL`)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	packagistCacheDuration = 30 * time.Minute
+	packagistDefaultAPI    = "https://packagist.org"
+)
+
+// #configStore packagist.api - string - Base URL of the Packagist API (defaults to https://packagist.org)
+const configKeyPackagistAPI = "packagist.api"
+
+func init() {
+	registerServiceHandler("packagist", packagistServiceHandler{})
+}
+
+type packagistServiceHandler struct{}
+
+type packagistVersion struct {
+	Version string            `json:"version"`
+	License []string          `json:"license"`
+	Require map[string]string `json:"require"`
+}
+
+type packagistPackage struct {
+	Package struct {
+		Downloads struct {
+			Total   int64 `json:"total"`
+			Monthly int64 `json:"monthly"`
+			Daily   int64 `json:"daily"`
+		} `json:"downloads"`
+		Versions map[string]packagistVersion `json:"versions"`
+	} `json:"package"`
+}
+
+func (packagistServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "Packagist version",
+			DemoPath:    "/packagist/version/laravel/framework",
+			Arguments:   []string{"version", "<vendor>", "<package>", "[prerelease]"},
+		},
+		{
+			ServiceName: "Packagist downloads",
+			DemoPath:    "/packagist/downloads/laravel/framework/monthly",
+			Arguments:   []string{"downloads", "<vendor>", "<package>", "[total|monthly|daily]"},
+		},
+		{
+			ServiceName: "Packagist license",
+			DemoPath:    "/packagist/license/laravel/framework",
+			Arguments:   []string{"license", "<vendor>", "<package>"},
+		},
+		{
+			ServiceName: "Packagist required PHP version",
+			DemoPath:    "/packagist/runtime/laravel/framework",
+			Arguments:   []string{"runtime", "<vendor>", "<package>"},
+		},
+	}
+}
+
+func (packagistServiceHandler) IsEnabled() bool { return true }
+
+func (p packagistServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 3 { //nolint:gomnd
+		err = errors.New("you need to provide command, vendor and package")
+		return title, text, color, err
+	}
+
+	var fetch func(context.Context, string, []string) (string, error)
+	switch params[0] {
+	case "version":
+		title, fetch = "packagist", p.fetchVersion
+	case "downloads":
+		title, fetch = "downloads", p.fetchDownloads
+	case "license":
+		title, fetch = "license", p.fetchLicense
+	case "runtime":
+		title, fetch = "php", p.fetchRuntime
+	default:
+		err = errors.New("an unknown service command was called")
+		return title, text, color, err
+	}
+
+	cacheKey := strings.Join(params, "/")
+	text, err = cacheStore.Get("packagist", cacheKey)
+
+	if err != nil {
+		if text, err = fetch(ctx, params[1]+"/"+params[2], params[3:]); err != nil {
+			return title, text, color, err
+		}
+
+		logErr(cacheStore.Set("packagist", cacheKey, text, packagistCacheDuration), "writing Packagist result to cache")
+	}
+
+	return title, text, registryBadgeColor(params[0], text), nil
+}
+
+func (p packagistServiceHandler) fetchVersion(ctx context.Context, pkg string, opts []string) (string, error) {
+	v, err := p.fetchLatestVersion(ctx, pkg, len(opts) > 0 && opts[0] == "prerelease")
+	if err != nil {
+		return "", err
+	}
+
+	return v.Version, nil
+}
+
+func (p packagistServiceHandler) fetchDownloads(ctx context.Context, pkg string, opts []string) (string, error) {
+	period := "total"
+	if len(opts) > 0 {
+		period = opts[0]
+	}
+
+	info, err := p.fetchPackage(ctx, pkg)
+	if err != nil {
+		return "", err
+	}
+
+	switch period {
+	case "total":
+		return metricFormat(info.Package.Downloads.Total), nil
+	case "monthly":
+		return metricFormat(info.Package.Downloads.Monthly) + registryPeriodSuffix(period), nil
+	case "daily":
+		return metricFormat(info.Package.Downloads.Daily) + registryPeriodSuffix(period), nil
+	default:
+		return "", errors.Errorf("unsupported period %q", period)
+	}
+}
+
+func (p packagistServiceHandler) fetchLicense(ctx context.Context, pkg string, _ []string) (string, error) {
+	v, err := p.fetchLatestVersion(ctx, pkg, false)
+	if err != nil {
+		return "", err
+	}
+
+	if len(v.License) == 0 {
+		return "None", nil
+	}
+
+	return strings.Join(v.License, ", "), nil
+}
+
+func (p packagistServiceHandler) fetchRuntime(ctx context.Context, pkg string, _ []string) (string, error) {
+	v, err := p.fetchLatestVersion(ctx, pkg, false)
+	if err != nil {
+		return "", err
+	}
+
+	if php := v.Require["php"]; php != "" {
+		return php, nil
+	}
+
+	return "any", nil
+}
+
+func (p packagistServiceHandler) fetchLatestVersion(ctx context.Context, pkg string, prerelease bool) (*packagistVersion, error) {
+	info, err := p.fetchPackage(ctx, pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Branches are listed as dev-<branch> and are ignored as they
+	// are not valid semver
+	latest := latestVersion(sortedKeys(info.Package.Versions), prerelease)
+	if latest == "" {
+		return nil, errors.New("package has no tagged versions")
+	}
+
+	v := info.Package.Versions[latest]
+	return &v, nil
+}
+
+func (packagistServiceHandler) fetchPackage(ctx context.Context, pkg string) (*packagistPackage, error) {
+	base := strings.TrimRight(configStore.StrDefault(configKeyPackagistAPI, packagistDefaultAPI), "/")
+
+	p := &packagistPackage{}
+	req, _ := http.NewRequestWithContext(ctx, "GET", base+"/packages/"+pkg+".json", nil)
+	if err := fetchJSON(req, p); err != nil {
+		return nil, errors.Wrap(err, "fetching package")
+	}
+
+	return p, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+const (
+	pushMaxBodySize = 64 * 1024 // 64KiB
+	// pushRetention is the time a pushed value is kept after it was
+	// last updated
+	pushRetention = 365 * 24 * time.Hour
+)
+
+// #configStore push.<namespace>.token - string - Token required to push values into <namespace> (only configured namespaces accept values, pushed values are kept in memory only)
+const configKeyPushToken = "push.%s.token"
+
+func init() {
+	registerServiceHandler("push", pushServiceHandler{})
+}
+
+type (
+	pushServiceHandler struct{}
+
+	pushedBadge struct {
+		Title string `json:"title"`
+		Text  string `json:"text"`
+		Color string `json:"color"`
+	}
+)
+
+func (pushServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{{
+		ServiceName: "Pushed badge value",
+		DemoPath:    "/push/ci/benchmark",
+		Arguments:   []string{"<namespace>", "<key>"},
+	}}
+}
+
+func (pushServiceHandler) IsEnabled() bool { return true }
+
+func (pushServiceHandler) Handle(_ context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide namespace and key")
+		return title, text, color, err
+	}
+
+	storageKey, err := pushStorageKey(params[0], params[1])
+	if err != nil {
+		return title, text, color, err
+	}
+
+	stored, err := cacheStore.Get("push", storageKey)
+	if err != nil {
+		return params[1], "no data", colorNameLightGray, nil
+	}
+
+	b := pushedBadge{}
+	if err = json.Unmarshal([]byte(stored), &b); err != nil {
+		return title, text, color, errors.Wrap(err, "decoding pushed badge")
+	}
+
+	return b.Title, b.Text, b.Color, nil
+}
+
+// handlePush stores the badge values pushed as JSON or form values
+// for the namespace and key given in the path. The values are kept in
+// the cache until overwritten or expired after pushRetention.
+func handlePush(res http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	namespace, err := url.QueryUnescape(vars["namespace"])
+	if err != nil {
+		http.Error(res, "Invalid escaping in URL", http.StatusBadRequest)
+		return
+	}
+
+	key, err := url.QueryUnescape(vars["key"])
+	if err != nil {
+		http.Error(res, "Invalid escaping in URL", http.StatusBadRequest)
+		return
+	}
+
+	storageKey, err := pushStorageKey(namespace, key)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token := configStore.Str(fmt.Sprintf(configKeyPushToken, namespace))
+	if token == "" {
+		http.Error(res, "Namespace not found: "+namespace, http.StatusNotFound)
+		return
+	}
+
+	if !hasBearerToken(r, token) {
+		http.Error(res, "Invalid push token", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(res, r.Body, pushMaxBodySize)
+
+	b := pushedBadge{}
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct == "application/json" {
+		if err = json.NewDecoder(r.Body).Decode(&b); err != nil {
+			http.Error(res, "Unable to parse JSON body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err = r.ParseForm(); err != nil {
+			http.Error(res, "Unable to parse form body", http.StatusBadRequest)
+			return
+		}
+		b = pushedBadge{Title: r.Form.Get("title"), Text: r.Form.Get("text"), Color: r.Form.Get("color")}
+	}
+
+	if b.Title == "" || b.Text == "" {
+		http.Error(res, "You must specify parameters 'title' and 'text'.", http.StatusBadRequest)
+		return
+	}
+
+	if b.Color == "" {
+		b.Color = defaultColor
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		logrus.WithError(err).Error("encoding pushed badge")
+		http.Error(res, "Unable to store badge", http.StatusInternalServerError)
+		return
+	}
+
+	if err = cacheStore.Set("push", storageKey, string(data), pushRetention); err != nil {
+		logrus.WithError(err).Error("storing pushed badge")
+		http.Error(res, "Unable to store badge", http.StatusInternalServerError)
+		return
+	}
+
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// pushStorageKey joins namespace and key into the key the badge is
+// stored at, slashes are rejected as they would make it ambiguous
+func pushStorageKey(namespace, key string) (string, error) {
+	if strings.Contains(namespace, "/") || strings.Contains(key, "/") {
+		return "", errors.New("namespace and key must not contain slashes")
+	}
+
+	return namespace + "/" + key, nil
+}
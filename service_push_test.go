@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpPush(t *testing.T) {
+	push := func(path, token, contentType, body string) int {
+		headers := map[string]string{"Content-Type": contentType}
+		if token != "" {
+			headers["Authorization"] = "Bearer " + token
+		}
+
+		resp, _ := doRequest(t, "PUT", path, strings.NewReader(body), headers)
+		return resp.Code
+	}
+
+	configStore["push.ci.token"] = "s3cr3t"
+	defer delete(configStore, "push.ci.token")
+
+	form := "application/x-www-form-urlencoded"
+	formBody := "title=bench&text=42ns&color=blue"
+
+	assert.Equal(t, http.StatusNotFound, push("/push/unknown/bench", "s3cr3t", form, formBody), "unknown namespace")
+	assert.Equal(t, http.StatusUnauthorized, push("/push/ci/bench", "", form, formBody), "missing token")
+	assert.Equal(t, http.StatusUnauthorized, push("/push/ci/bench", "wrong", form, formBody), "wrong token")
+	assert.Equal(t, http.StatusBadRequest, push("/push/ci/a%2Fb", "s3cr3t", form, formBody), "slash in key")
+	assert.Equal(t, http.StatusBadRequest, push("/push/ci/bench", "s3cr3t", form, "title=bench"), "missing text")
+	assert.Equal(t, http.StatusBadRequest, push("/push/ci/size", "s3cr3t", "application/json", "{"), "invalid JSON")
+
+	assert.Equal(t, http.StatusNoContent, push("/push/ci/bench", "s3cr3t", form, formBody))
+	assert.Equal(t, http.StatusNoContent, push("/push/ci/size", "s3cr3t", "application/json", `{"title":"size","text":"12MB"}`))
+
+	for path, expect := range map[string][]string{
+		"/push/ci/bench":   {">bench</text>", ">42ns</text>", "#007ec6"},
+		"/push/ci/size":    {">size</text>", ">12MB</text>", "#4c1"},
+		"/push/ci/missing": {">missing</text>", ">no data</text>"},
+	} {
+		resp, p := doRequest(t, "GET", path, nil, nil)
+		assert.Equal(t, http.StatusOK, resp.Code, path)
+		for _, e := range expect {
+			assert.Contains(t, p, e, path)
+		}
+	}
+}
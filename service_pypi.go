@@ -0,0 +1,225 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	pypiCacheDuration  = 30 * time.Minute
+	pypiDefaultIndex   = "https://pypi.org"
+	pypiDefaultStats   = "https://pypistats.org"
+	pypiMaxLicenseText = 40
+)
+
+const (
+	// #configStore pypi.index - string - Base URL of the PyPI JSON API (defaults to https://pypi.org)
+	configKeyPyPIIndex = "pypi.index"
+	// #configStore pypi.stats_api - string - Base URL of the pypistats API (defaults to https://pypistats.org)
+	configKeyPyPIStats = "pypi.stats_api"
+)
+
+var pypiPythonClassifier = regexp.MustCompile(`^Programming Language :: Python :: (\d+\.\d+)$`)
+
+func init() {
+	registerServiceHandler("pypi", pypiServiceHandler{})
+}
+
+type pypiServiceHandler struct{}
+
+type pypiPackage struct {
+	Info struct {
+		Version           string   `json:"version"`
+		License           string   `json:"license"`
+		LicenseExpression string   `json:"license_expression"`
+		Classifiers       []string `json:"classifiers"`
+		RequiresPython    string   `json:"requires_python"`
+	} `json:"info"`
+	Releases map[string][]struct {
+		Yanked bool `json:"yanked"`
+	} `json:"releases"`
+}
+
+func (pypiServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "PyPI version",
+			DemoPath:    "/pypi/version/requests",
+			Arguments:   []string{"version", "<package>", "[prerelease]"},
+		},
+		{
+			ServiceName: "PyPI downloads",
+			DemoPath:    "/pypi/downloads/requests/monthly",
+			Arguments:   []string{"downloads", "<package>", "[daily|weekly|monthly]"},
+		},
+		{
+			ServiceName: "PyPI license",
+			DemoPath:    "/pypi/license/requests",
+			Arguments:   []string{"license", "<package>"},
+		},
+		{
+			ServiceName: "PyPI Python versions",
+			DemoPath:    "/pypi/runtime/requests",
+			Arguments:   []string{"runtime", "<package>"},
+		},
+	}
+}
+
+func (pypiServiceHandler) IsEnabled() bool { return true }
+
+func (p pypiServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide command and package")
+		return title, text, color, err
+	}
+
+	var fetch func(context.Context, string, []string) (string, error)
+	switch params[0] {
+	case "version":
+		title, fetch = "pypi", p.fetchVersion
+	case "downloads":
+		title, fetch = "downloads", p.fetchDownloads
+	case "license":
+		title, fetch = "license", p.fetchLicense
+	case "runtime":
+		title, fetch = "python", p.fetchRuntime
+	default:
+		err = errors.New("an unknown service command was called")
+		return title, text, color, err
+	}
+
+	cacheKey := strings.Join(params, "/")
+	text, err = cacheStore.Get("pypi", cacheKey)
+
+	if err != nil {
+		if text, err = fetch(ctx, params[1], params[2:]); err != nil {
+			return title, text, color, err
+		}
+
+		logErr(cacheStore.Set("pypi", cacheKey, text, pypiCacheDuration), "writing PyPI result to cache")
+	}
+
+	return title, text, registryBadgeColor(params[0], text), nil
+}
+
+func (p pypiServiceHandler) fetchVersion(ctx context.Context, pkg string, opts []string) (string, error) {
+	pkgInfo, err := p.fetchPackage(ctx, pkg)
+	if err != nil {
+		return "", err
+	}
+
+	if len(opts) == 0 || opts[0] != "prerelease" {
+		return pkgInfo.Info.Version, nil
+	}
+
+	// PEP 440 versions are not semver so they are compared using the
+	// loose version comparison, releases having only yanked files are
+	// ignored
+	latest := pkgInfo.Info.Version
+	for v, files := range pkgInfo.Releases {
+		yanked := true
+		for _, f := range files {
+			yanked = yanked && f.Yanked
+		}
+
+		if !yanked && compareLooseVersion(v, latest) > 0 {
+			latest = v
+		}
+	}
+
+	return latest, nil
+}
+
+func (pypiServiceHandler) fetchDownloads(ctx context.Context, pkg string, opts []string) (string, error) {
+	period := "monthly"
+	if len(opts) > 0 {
+		period = opts[0]
+	}
+
+	r := struct {
+		Data map[string]int64 `json:"data"`
+	}{}
+
+	field, ok := map[string]string{
+		"daily":   "last_day",
+		"weekly":  "last_week",
+		"monthly": "last_month",
+	}[period]
+	if !ok {
+		return "", errors.Errorf("unsupported period %q", period)
+	}
+
+	base := strings.TrimRight(configStore.StrDefault(configKeyPyPIStats, pypiDefaultStats), "/")
+	req, _ := http.NewRequestWithContext(ctx, "GET", strings.Join([]string{base, "api", "packages", strings.ToLower(pkg), "recent"}, "/"), nil)
+	if err := fetchJSON(req, &r); err != nil {
+		return "", err
+	}
+
+	return metricFormat(r.Data[field]) + registryPeriodSuffix(period), nil
+}
+
+func (p pypiServiceHandler) fetchLicense(ctx context.Context, pkg string, _ []string) (string, error) {
+	pkgInfo, err := p.fetchPackage(ctx, pkg)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case pkgInfo.Info.LicenseExpression != "":
+		return pkgInfo.Info.LicenseExpression, nil
+
+	case pkgInfo.Info.License != "" && len(pkgInfo.Info.License) < pypiMaxLicenseText && !strings.Contains(pkgInfo.Info.License, "\n"):
+		return pkgInfo.Info.License, nil
+	}
+
+	// Some packages put the whole license text into the field, the
+	// trove classifier is more reliable in that case
+	for _, c := range pkgInfo.Info.Classifiers {
+		if strings.HasPrefix(c, "License :: ") {
+			parts := strings.Split(c, " :: ")
+			return parts[len(parts)-1], nil
+		}
+	}
+
+	return "None", nil
+}
+
+func (p pypiServiceHandler) fetchRuntime(ctx context.Context, pkg string, _ []string) (string, error) {
+	pkgInfo, err := p.fetchPackage(ctx, pkg)
+	if err != nil {
+		return "", err
+	}
+
+	var versions []string
+	for _, c := range pkgInfo.Info.Classifiers {
+		if m := pypiPythonClassifier.FindStringSubmatch(c); m != nil {
+			versions = append(versions, m[1])
+		}
+	}
+
+	switch {
+	case len(versions) > 0:
+		return strings.Join(versions, " | "), nil
+	case pkgInfo.Info.RequiresPython != "":
+		return pkgInfo.Info.RequiresPython, nil
+	default:
+		return "any", nil
+	}
+}
+
+func (pypiServiceHandler) fetchPackage(ctx context.Context, pkg string) (*pypiPackage, error) {
+	base := strings.TrimRight(configStore.StrDefault(configKeyPyPIIndex, pypiDefaultIndex), "/")
+
+	p := &pypiPackage{}
+	req, _ := http.NewRequestWithContext(ctx, "GET", strings.Join([]string{base, "pypi", pkg, "json"}, "/"), nil)
+	if err := fetchJSON(req, p); err != nil {
+		return nil, errors.Wrap(err, "fetching package")
+	}
+
+	return p, nil
+}
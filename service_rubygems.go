@@ -0,0 +1,178 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const (
+	rubygemsCacheDuration = 30 * time.Minute
+	rubygemsDefaultAPI    = "https://rubygems.org"
+)
+
+// #configStore rubygems.api - string - Base URL of the RubyGems API (defaults to https://rubygems.org)
+const configKeyRubyGemsAPI = "rubygems.api"
+
+func init() {
+	registerServiceHandler("rubygems", rubygemsServiceHandler{})
+}
+
+type rubygemsServiceHandler struct{}
+
+type rubygemsVersion struct {
+	Number      string   `json:"number"`
+	Prerelease  bool     `json:"prerelease"`
+	Licenses    []string `json:"licenses"`
+	RubyVersion string   `json:"ruby_version"`
+}
+
+func (rubygemsServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "RubyGems version",
+			DemoPath:    "/rubygems/version/rails",
+			Arguments:   []string{"version", "<gem>", "[prerelease]"},
+		},
+		{
+			ServiceName: "RubyGems downloads",
+			DemoPath:    "/rubygems/downloads/rails",
+			Arguments:   []string{"downloads", "<gem>", "[total|version]"},
+		},
+		{
+			ServiceName: "RubyGems license",
+			DemoPath:    "/rubygems/license/rails",
+			Arguments:   []string{"license", "<gem>"},
+		},
+		{
+			ServiceName: "RubyGems required Ruby version",
+			DemoPath:    "/rubygems/runtime/rails",
+			Arguments:   []string{"runtime", "<gem>"},
+		},
+	}
+}
+
+func (rubygemsServiceHandler) IsEnabled() bool { return true }
+
+func (r rubygemsServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 2 { //nolint:gomnd
+		err = errors.New("you need to provide command and gem")
+		return title, text, color, err
+	}
+
+	var fetch func(context.Context, string, []string) (string, error)
+	switch params[0] {
+	case "version":
+		title, fetch = "gem", r.fetchVersion
+	case "downloads":
+		title, fetch = "downloads", r.fetchDownloads
+	case "license":
+		title, fetch = "license", r.fetchLicense
+	case "runtime":
+		title, fetch = "ruby", r.fetchRuntime
+	default:
+		err = errors.New("an unknown service command was called")
+		return title, text, color, err
+	}
+
+	cacheKey := strings.Join(params, "/")
+	text, err = cacheStore.Get("rubygems", cacheKey)
+
+	if err != nil {
+		if text, err = fetch(ctx, params[1], params[2:]); err != nil {
+			return title, text, color, err
+		}
+
+		logErr(cacheStore.Set("rubygems", cacheKey, text, rubygemsCacheDuration), "writing RubyGems result to cache")
+	}
+
+	return title, text, registryBadgeColor(params[0], text), nil
+}
+
+func (r rubygemsServiceHandler) fetchVersion(ctx context.Context, gem string, opts []string) (string, error) {
+	v, err := r.fetchLatestVersion(ctx, gem, len(opts) > 0 && opts[0] == "prerelease")
+	if err != nil {
+		return "", err
+	}
+
+	return v.Number, nil
+}
+
+func (r rubygemsServiceHandler) fetchDownloads(ctx context.Context, gem string, opts []string) (string, error) {
+	period := "total"
+	if len(opts) > 0 {
+		period = opts[0]
+	}
+
+	g := struct {
+		Downloads        int64 `json:"downloads"`
+		VersionDownloads int64 `json:"version_downloads"`
+	}{}
+
+	if err := r.fetchAPI(ctx, "gems/"+gem+".json", &g); err != nil {
+		return "", err
+	}
+
+	switch period {
+	case "total":
+		return metricFormat(g.Downloads), nil
+	case "version":
+		return metricFormat(g.VersionDownloads) + "/version", nil
+	default:
+		return "", errors.Errorf("unsupported period %q", period)
+	}
+}
+
+func (r rubygemsServiceHandler) fetchLicense(ctx context.Context, gem string, _ []string) (string, error) {
+	v, err := r.fetchLatestVersion(ctx, gem, false)
+	if err != nil {
+		return "", err
+	}
+
+	if len(v.Licenses) == 0 {
+		return "None", nil
+	}
+
+	return strings.Join(v.Licenses, ", "), nil
+}
+
+func (r rubygemsServiceHandler) fetchRuntime(ctx context.Context, gem string, _ []string) (string, error) {
+	v, err := r.fetchLatestVersion(ctx, gem, false)
+	if err != nil {
+		return "", err
+	}
+
+	if v.RubyVersion == "" {
+		return "any", nil
+	}
+
+	return v.RubyVersion, nil
+}
+
+// fetchLatestVersion retrieves the newest version of the gem, the
+// RubyGems API orders the versions newest first
+func (r rubygemsServiceHandler) fetchLatestVersion(ctx context.Context, gem string, prerelease bool) (*rubygemsVersion, error) {
+	var versions []rubygemsVersion
+
+	if err := r.fetchAPI(ctx, "versions/"+gem+".json", &versions); err != nil {
+		return nil, err
+	}
+
+	for i := range versions {
+		if prerelease || !versions[i].Prerelease {
+			return &versions[i], nil
+		}
+	}
+
+	return nil, errors.New("gem has no versions")
+}
+
+func (rubygemsServiceHandler) fetchAPI(ctx context.Context, path string, out interface{}) error {
+	base := strings.TrimRight(configStore.StrDefault(configKeyRubyGemsAPI, rubygemsDefaultAPI), "/")
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", base+"/api/v1/"+path, nil)
+	return errors.Wrap(fetchJSON(req, out), "fetching gem")
+}
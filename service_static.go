@@ -13,27 +13,76 @@ func init() {
 type staticServiceHandler struct{}
 
 func (staticServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
-	return serviceHandlerDocumentationList{{
-		ServiceName: "Static Badge",
-		DemoPath:    "/static/API/Documentation/4c1",
-		Arguments:   []string{"<title>", "<text>", "[color]"},
-	}}
+	return serviceHandlerDocumentationList{
+		{
+			ServiceName: "Static Badge",
+			DemoPath:    "/static/API/Documentation/4c1",
+			Arguments:   []string{"<title>", "<text>", "[color]"},
+		},
+		{
+			ServiceName: "Static Badge with a single segment",
+			DemoPath:    "/static//sponsored/ea4aaa",
+			Arguments:   []string{"[title]", "[text]", "[color]"},
+		},
+		{
+			ServiceName: "Static Badge with multiple segments",
+			DemoPath:    "/static/build/linux/4c1/macos/4c1/windows/e05d44",
+			Arguments:   []string{"<title>", "<text>", "<color>", "<text>", "[color]", "..."},
+		},
+	}
 }
 
 func (staticServiceHandler) IsEnabled() bool { return true }
 
 func (staticServiceHandler) Handle(_ context.Context, params []string) (title, text, color string, err error) {
-	if len(params) < 2 { //nolint:gomnd
-		err = errors.New("you need to provide title and text")
-		return title, text, color, err
+	// A single parameter renders a badge consisting only of the text
+	if len(params) == 1 {
+		params = append([]string{""}, params...)
 	}
 
-	if len(params) < 3 { //nolint:gomnd
-		params = append(params, defaultColor)
+	if params[0] == "" && params[1] == "" {
+		err = errors.New("you need to provide title or text")
+		return title, text, color, err
 	}
 
 	title = params[0]
 	text = params[1]
-	color = params[2]
+	color = defaultColor
+	if len(params) > 2 && params[2] != "" { //nolint:gomnd
+		color = params[2]
+	}
+
 	return title, text, color, err
 }
+
+// HandleSegments renders the title followed by an arbitrary number
+// of text / color pairs
+func (s staticServiceHandler) HandleSegments(ctx context.Context, params []string) ([]badgeSegment, error) {
+	title, text, color, err := s.Handle(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := titleTextSegments(title, text, color)
+
+	// Badges consisting only of the title get the color of the text
+	// as they would be indistinguishable in label color otherwise
+	if text == "" && len(params) <= 3 { //nolint:gomnd
+		segments[0].Color = color
+		return segments, nil
+	}
+
+	if title == "" {
+		segments = segments[1:]
+	}
+
+	for i := 3; i < len(params); i += 2 {
+		seg := badgeSegment{Text: params[i], Color: defaultColor}
+		if i+1 < len(params) {
+			seg.Color = params[i+1]
+		}
+		segments = append(segments, seg)
+	}
+
+	return segments, nil
+}
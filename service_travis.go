@@ -1,25 +1,51 @@
 package main
 
 import (
-	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 )
 
-const travisCacheDuration = 5 * time.Minute
+const (
+	travisCacheDuration = 5 * time.Minute
+	travisDefaultAPI    = "https://api.travis-ci.com"
+)
+
+const (
+	// #configStore travis.token - string - API token for travis-ci.com (required for private repos)
+	configKeyTravisToken = "travis.token"
+	// #configStore travis.<host>.url - string - API base URL of the Travis Enterprise instance addressed as <host> (host is rejected if unset)
+	configKeyTravisEnterpriseURL = "travis.%s.url"
+	// #configStore travis.<host>.token - string - API token for the Travis Enterprise instance addressed as <host>
+	configKeyTravisEnterpriseToken = "travis.%s.token"
+)
 
 func init() {
 	registerServiceHandler("travis", travisServiceHandler{})
+	registerServiceHandler("travis-enterprise", travisServiceHandler{enterprise: true})
+}
+
+// travisServiceHandler serves travis-ci.com and, using a separate
+// service name to not collide with user names, Travis Enterprise
+// instances addressed by their configured host
+type travisServiceHandler struct {
+	enterprise bool
 }
 
-type travisServiceHandler struct{}
+func (t travisServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	if t.enterprise {
+		return serviceHandlerDocumentationList{{
+			ServiceName: "Travis Enterprise",
+			DemoPath:    "/travis-enterprise/travis.example.com/Luzifer/password",
+			Arguments:   []string{"<host>", "<user>", "<repo>", "[branch]"},
+		}}
+	}
 
-func (travisServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
 	return serviceHandlerDocumentationList{{
 		ServiceName: "Travis-CI",
 		DemoPath:    "/travis/Luzifer/password",
@@ -29,59 +55,95 @@ func (travisServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
 
 func (travisServiceHandler) IsEnabled() bool { return true }
 
-func (travisServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+func (t travisServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	apiBase, token := travisDefaultAPI, configStore.Str(configKeyTravisToken)
+
+	if t.enterprise {
+		if len(params) < 1 {
+			err = errors.New("you need to provide the enterprise host")
+			return title, text, color, err
+		}
+
+		if apiBase = configStore.Str(fmt.Sprintf(configKeyTravisEnterpriseURL, params[0])); apiBase == "" {
+			err = fmt.Errorf("travis enterprise %q is not configured", params[0])
+			return title, text, color, err
+		}
+
+		token = configStore.Str(fmt.Sprintf(configKeyTravisEnterpriseToken, params[0]))
+		params = params[1:]
+	}
+
 	if len(params) < 2 { //nolint:gomnd
 		err = errors.New("you need to provide user and repo")
 		return title, text, color, err
 	}
 
-	if len(params) < 3 { //nolint:gomnd
-		params = append(params, "master")
-	}
-
-	path := strings.Join([]string{"repos", params[0], params[1], "branches", params[2]}, "/")
+	slug := url.PathEscape(strings.Join([]string{params[0], params[1]}, "/"))
+	cacheKey := strings.Join(append([]string{apiBase}, params...), "/")
 
 	var state string
-	state, err = cacheStore.Get("travis", path)
+	state, err = cacheStore.Get("travis", cacheKey)
 
 	if err != nil {
-		var resp *http.Response
-		req, _ := http.NewRequestWithContext(ctx, "GET", "https://api.travis-ci.org/"+path, nil)
-		resp, err = http.DefaultClient.Do(req)
-		if err != nil {
-			return title, text, color, errors.Wrap(err, "executing request")
-		}
-		defer func() {
-			if err := resp.Body.Close(); err != nil {
-				logrus.WithError(err).Error("closing request body (leaked fd)")
+		var branch string
+		if len(params) > 2 { //nolint:gomnd
+			branch = params[2]
+		} else {
+			if branch, err = t.fetchDefaultBranch(ctx, apiBase, token, slug); err != nil {
+				return title, text, color, err
 			}
-		}()
+		}
 
 		r := struct {
-			File   string `json:"file"`
-			Branch struct {
+			LastBuild *struct {
 				State string `json:"state"`
-			} `json:"branch"`
+			} `json:"last_build"`
 		}{}
 
-		if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
-			return title, text, color, errors.Wrap(err, "decoding JSON response")
+		if err = t.fetchAPI(ctx, apiBase, token, strings.Join([]string{"repo", slug, "branch", url.PathEscape(branch)}, "/"), &r); err != nil {
+			return title, text, color, err
+		}
+
+		if r.LastBuild != nil {
+			state = r.LastBuild.State
 		}
-		state = r.Branch.State
-		logErr(cacheStore.Set("travis", path, state, travisCacheDuration), "writing Travis status to cache")
+		logErr(cacheStore.Set("travis", cacheKey, state, travisCacheDuration), "writing Travis status to cache")
 	}
 
 	title = "travis"
 	text = state
 	if text == "" {
-		text = "unknown"
+		text = ciStatusUnknown
 	}
-	color = map[string]string{
-		"unknown":  "9f9f9f",
-		"passed":   "4c1",
-		"failed":   "e05d44",
-		"canceled": "9f9f9f",
-	}[text]
+	color = ciStatusColor(text)
 
 	return title, text, color, nil
 }
+
+func (t travisServiceHandler) fetchDefaultBranch(ctx context.Context, apiBase, token, slug string) (string, error) {
+	r := struct {
+		DefaultBranch struct {
+			Name string `json:"name"`
+		} `json:"default_branch"`
+	}{}
+
+	if err := t.fetchAPI(ctx, apiBase, token, "repo/"+slug, &r); err != nil {
+		return "", errors.Wrap(err, "fetching repository")
+	}
+
+	if r.DefaultBranch.Name == "" {
+		return "", errors.New("repository has no default branch")
+	}
+
+	return r.DefaultBranch.Name, nil
+}
+
+func (travisServiceHandler) fetchAPI(ctx context.Context, apiBase, token, path string, out interface{}) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(apiBase, "/")+"/"+path, nil)
+	req.Header.Set("Travis-API-Version", "3")
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+
+	return fetchJSON(req, out)
+}
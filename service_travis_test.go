@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestTravisEnterprise(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/repo/enterprise%2Fpassword":
+			fmt.Fprint(w, `{"default_branch":{"name":"main"}}`)
+		case "/repo/enterprise%2Fpassword/branch/main":
+			fmt.Fprint(w, `{"last_build":{"state":"passed"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	configStore["travis.test.url"] = srv.URL
+	defer delete(configStore, "travis.test.url")
+
+	// The owner "enterprise" must not be mistaken for the host
+	_, text, color, err := travisServiceHandler{enterprise: true}.Handle(context.Background(), []string{"test", "enterprise", "password"})
+	assert.NoError(t, err)
+	assert.Equal(t, "passed", text)
+	assert.Equal(t, ciStatusColor("passed"), color)
+
+	_, _, _, err = travisServiceHandler{enterprise: true}.Handle(context.Background(), []string{"unknown", "Luzifer", "password"})
+	assert.EqualError(t, err, `travis enterprise "unknown" is not configured`)
+}
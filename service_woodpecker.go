@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const woodpeckerCacheDuration = 5 * time.Minute
+
+const (
+	// #configStore drone.<host>.url - string - Base URL of the Drone instance addressed as <host> (host is rejected if unset)
+	configKeyDroneURL = "drone.%s.url"
+	// #configStore drone.<host>.token - string - API token for the Drone instance addressed as <host>
+	configKeyDroneToken = "drone.%s.token"
+	// #configStore woodpecker.<host>.url - string - Base URL of the Woodpecker instance addressed as <host> (host is rejected if unset)
+	configKeyWoodpeckerURL = "woodpecker.%s.url"
+	// #configStore woodpecker.<host>.token - string - API token for the Woodpecker instance addressed as <host>
+	configKeyWoodpeckerToken = "woodpecker.%s.token"
+)
+
+// droneStates translates the build states of Drone and Woodpecker
+// (which share their heritage) into the normalized CI states
+var droneStates = map[string]string{
+	"success":  "passed",
+	"failure":  "failed",
+	"error":    "errored",
+	"killed":   "canceled",
+	"declined": "canceled",
+	"skipped":  "skipped",
+	"blocked":  "blocked",
+	"running":  "running",
+	"started":  "running",
+	"pending":  "pending",
+}
+
+func init() {
+	registerServiceHandler("drone", woodpeckerServiceHandler{drone: true})
+	registerServiceHandler("woodpecker", woodpeckerServiceHandler{})
+}
+
+type woodpeckerServiceHandler struct {
+	// drone switches the handler to the Drone API which still
+	// addresses repos by owner / name instead of numeric IDs
+	drone bool
+}
+
+func (w woodpeckerServiceHandler) GetDocumentation() serviceHandlerDocumentationList {
+	if w.drone {
+		return serviceHandlerDocumentationList{{
+			ServiceName: "Drone build status",
+			DemoPath:    "/drone/drone.example.com/Luzifer/badge-gen",
+			Arguments:   []string{"<host>", "<owner>", "<repo>", "[branch]"},
+		}}
+	}
+
+	return serviceHandlerDocumentationList{{
+		ServiceName: "Woodpecker pipeline status",
+		DemoPath:    "/woodpecker/ci.woodpecker-ci.org/woodpecker-ci/woodpecker",
+		Arguments:   []string{"<host>", "<owner>", "<repo>", "[branch]"},
+	}}
+}
+
+func (woodpeckerServiceHandler) IsEnabled() bool { return true }
+
+func (w woodpeckerServiceHandler) Handle(ctx context.Context, params []string) (title, text, color string, err error) {
+	if len(params) < 3 { //nolint:gomnd
+		err = errors.New("you need to provide host, owner and repo")
+		return title, text, color, err
+	}
+
+	keyURL, keyToken, service := configKeyWoodpeckerURL, configKeyWoodpeckerToken, "woodpecker"
+	if w.drone {
+		keyURL, keyToken, service = configKeyDroneURL, configKeyDroneToken, "drone"
+	}
+
+	base := strings.TrimRight(configStore.Str(fmt.Sprintf(keyURL, params[0])), "/")
+	if base == "" {
+		err = fmt.Errorf("%s %q is not configured", service, params[0])
+		return title, text, color, err
+	}
+	token := configStore.Str(fmt.Sprintf(keyToken, params[0]))
+
+	cacheKey := strings.Join(params, "/")
+	text, err = cacheStore.Get(service, cacheKey)
+
+	if err != nil {
+		var reqURL string
+		if w.drone {
+			reqURL = strings.Join([]string{base, "api", "repos", url.PathEscape(params[1]), url.PathEscape(params[2]), "builds", "latest"}, "/")
+		} else {
+			var repoID int64
+			if repoID, err = w.fetchWoodpeckerRepoID(ctx, base, token, params[1], params[2]); err != nil {
+				return title, text, color, err
+			}
+			reqURL = fmt.Sprintf("%s/api/repos/%d/pipelines/latest", base, repoID)
+		}
+
+		if len(params) > 3 { //nolint:gomnd
+			reqURL += "?" + url.Values{"branch": []string{params[3]}}.Encode()
+		}
+
+		r := struct {
+			Status string `json:"status"`
+		}{}
+
+		if err = fetchJSON(w.newRequest(ctx, reqURL, token), &r); err != nil {
+			return title, text, color, err
+		}
+
+		text = ciStatusUnknown
+		if s, ok := droneStates[r.Status]; ok {
+			text = s
+		}
+
+		logErr(cacheStore.Set(service, cacheKey, text, woodpeckerCacheDuration), "writing "+service+" status to cache")
+	}
+
+	return "build", text, ciStatusColor(text), nil
+}
+
+func (w woodpeckerServiceHandler) fetchWoodpeckerRepoID(ctx context.Context, base, token, owner, repo string) (int64, error) {
+	r := struct {
+		ID int64 `json:"id"`
+	}{}
+
+	reqURL := strings.Join([]string{base, "api", "repos", "lookup", url.PathEscape(owner), url.PathEscape(repo)}, "/")
+	if err := fetchJSON(w.newRequest(ctx, reqURL, token), &r); err != nil {
+		return 0, errors.Wrap(err, "looking up repo")
+	}
+
+	return r.ID, nil
+}
+
+func (woodpeckerServiceHandler) newRequest(ctx context.Context, reqURL, token string) *http.Request {
+	req, _ := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
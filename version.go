@@ -0,0 +1,143 @@
+package main
+
+import (
+	"cmp"
+	"regexp"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// latestVersion returns the highest semantic version in the given
+// list in its original notation. Versions not parseable as semver
+// are ignored, prereleases only count when requested. If no version
+// qualifies an empty string is returned.
+func latestVersion(versions []string, prerelease bool) string {
+	var latest, latestCanon string
+
+	for _, v := range versions {
+		canon := canonicalVersion(v)
+		if !semver.IsValid(canon) {
+			continue
+		}
+
+		if semver.Prerelease(canon) != "" && !prerelease {
+			continue
+		}
+
+		if latestCanon == "" || semver.Compare(canon, latestCanon) > 0 {
+			latest, latestCanon = v, canon
+		}
+	}
+
+	return latest
+}
+
+func canonicalVersion(v string) string {
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	return v
+}
+
+// versionColor colors a version orange while it is still in the
+// unstable 0.x range and blue otherwise
+func versionColor(v string) string {
+	if strings.HasPrefix(canonicalVersion(v), "v0.") {
+		return colorNameOrange
+	}
+	return colorNameBlue
+}
+
+var (
+	looseVersionToken = regexp.MustCompile(`\d+|[a-zA-Z]+`)
+
+	// looseVersionPostRelease contains the alphabetic parts marking a
+	// release made after the version (1.0.post1, 1.0_p1, 1.0-patch2)
+	looseVersionPostRelease = map[string]bool{
+		"p": true, "patch": true, "pl": true, "post": true, "rev": true,
+	}
+)
+
+// compareLooseVersion compares two versions not following semver
+// the way most package managers do: numeric parts are compared by
+// value, alphabetic parts lexically. A trailing alphabetic part
+// (1.0rc1) sorts before the release (1.0) while additional numeric
+// parts (1.0.1) and post-releases (1.0.post1) sort after it.
+func compareLooseVersion(a, b string) int {
+	ta, tb := looseVersionToken.FindAllString(a, -1), looseVersionToken.FindAllString(b, -1)
+
+	for i := 0; i < len(ta) || i < len(tb); i++ {
+		switch {
+		case i >= len(ta):
+			return -looseVersionTailOrder(tb[i])
+		case i >= len(tb):
+			return looseVersionTailOrder(ta[i])
+		}
+
+		if c := compareLooseVersionToken(ta[i], tb[i]); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// latestLooseVersion returns the highest version compared using
+// compareLooseVersion. Versions isPrerelease reports as prereleases
+// only count when requested. If no version qualifies an empty string
+// is returned.
+func latestLooseVersion(versions []string, prerelease bool, isPrerelease func(string) bool) string {
+	var latest string
+
+	for _, v := range versions {
+		if !prerelease && isPrerelease(v) {
+			continue
+		}
+
+		if latest == "" || compareLooseVersion(v, latest) > 0 {
+			latest = v
+		}
+	}
+
+	return latest
+}
+
+func compareLooseVersionToken(a, b string) int {
+	aNum, bNum := a[0] >= '0' && a[0] <= '9', b[0] >= '0' && b[0] <= '9'
+
+	switch {
+	case aNum && bNum:
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			return cmp.Compare(len(a), len(b))
+		}
+		return strings.Compare(a, b)
+
+	case aNum:
+		return 1
+
+	case bNum:
+		return -1
+
+	case looseVersionPostRelease[strings.ToLower(a)] != looseVersionPostRelease[strings.ToLower(b)]:
+		// Post-releases sort after prereleases of the same version
+		if looseVersionPostRelease[strings.ToLower(a)] {
+			return 1
+		}
+		return -1
+
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// looseVersionTailOrder determines whether a version having the
+// given additional token sorts after (1) or before (-1) the version
+// without that token
+func looseVersionTailOrder(token string) int {
+	if (token[0] >= '0' && token[0] <= '9') || looseVersionPostRelease[strings.ToLower(token)] {
+		return 1
+	}
+	return -1
+}
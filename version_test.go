@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLatestVersion(t *testing.T) {
+	versions := []string{"latest", "1.2.0", "v1.10.0", "1.9.3", "2.0.0-rc.1", "alpine"}
+
+	if v := latestVersion(versions, false); v != "v1.10.0" {
+		t.Errorf("Latest stable version did not match 'v1.10.0': '%s'", v)
+	}
+
+	if v := latestVersion(versions, true); v != "2.0.0-rc.1" {
+		t.Errorf("Latest version did not match '2.0.0-rc.1': '%s'", v)
+	}
+
+	if v := latestVersion([]string{"latest", "main"}, true); v != "" {
+		t.Errorf("Latest version of non-semver list was not empty: '%s'", v)
+	}
+}
+
+func TestCompareLooseVersion(t *testing.T) {
+	cases := []struct {
+		A, B   string
+		Expect int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.10", "1.9", 1},
+		{"1.0", "1.0.1", -1},
+		{"1.0rc1", "1.0", -1},
+		{"2023.10.01", "2023.9.30", 1},
+		{"1.2a", "1.2b", -1},
+		{"1.02", "1.2", 0},
+		{"1.0.post1", "1.0", 1},
+		{"1.0.post1", "1.0rc1", 1},
+		{"1.0.post1", "1.0.1", -1},
+		{"1.0.post2", "1.0.post10", -1},
+		{"1.0_p1", "1.0", 1},
+		{"1.2.3.4", "1.2.3", 1},
+		{"1.2.3.4", "1.2.4", -1},
+		{"1.2.3.4", "1.2.3-beta", 1},
+	}
+
+	for _, c := range cases {
+		if r := compareLooseVersion(c.A, c.B); r != c.Expect {
+			t.Errorf("Comparing %q to %q yielded %d, expected %d", c.A, c.B, r, c.Expect)
+		}
+	}
+}
+
+func TestLatestLooseVersion(t *testing.T) {
+	versions := []string{"30.0-jre", "31.1-jre", "32.0.0-rc1", "4.0-M1", "31.0.1-jre", "1.0-SNAPSHOT"}
+
+	if v := latestLooseVersion(versions, false, mavenPrerelease.MatchString); v != "31.1-jre" {
+		t.Errorf("Latest stable version was '%s'", v)
+	}
+
+	if v := latestLooseVersion(versions, true, mavenPrerelease.MatchString); v != "32.0.0-rc1" {
+		t.Errorf("Latest version was '%s'", v)
+	}
+
+	// Backports released after the latest version must not count
+	if v := latestLooseVersion([]string{"5.1.0", "4.2.9"}, true, mavenPrerelease.MatchString); v != "5.1.0" {
+		t.Errorf("Latest version with backport was '%s'", v)
+	}
+
+	if v := latestLooseVersion([]string{"1.0", "1.0.post1", "1.1rc1"}, false, func(v string) bool { return strings.Contains(v, "rc") }); v != "1.0.post1" {
+		t.Errorf("Latest stable version with post-release was '%s'", v)
+	}
+
+	// NuGet mixes semver with legacy four-part versions
+	if v := latestLooseVersion([]string{"1.2.3", "1.2.3.4", "1.2.4-beta", "1.2.2.9"}, false, nugetPrerelease); v != "1.2.3.4" {
+		t.Errorf("Latest stable version with four-part versions was '%s'", v)
+	}
+
+	if v := latestLooseVersion([]string{"1.0-beta-2", "2.0.0.RC1", "2.0b1"}, false, mavenPrerelease.MatchString); v != "" {
+		t.Errorf("Latest stable version of prereleases was '%s'", v)
+	}
+}